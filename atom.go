@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+type AtomFeed struct {
+	Title    AtomText    `xml:"title"`
+	Subtitle AtomText    `xml:"subtitle"`
+	Links    []AtomLink  `xml:"link"`
+	Entries  []AtomEntry `xml:"entry"`
+}
+
+type AtomEntry struct {
+	ID        string     `xml:"id"`
+	Title     AtomText   `xml:"title"`
+	Links     []AtomLink `xml:"link"`
+	Summary   AtomText   `xml:"summary"`
+	Content   AtomText   `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+// AtomText is an Atom text construct. type="xhtml" content is inline markup
+// rather than character data, so its raw XML is kept as well.
+type AtomText struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+	Body string `xml:",innerxml"`
+}
+
+// String returns the text, or for xhtml the markup inside the wrapping div.
+func (t AtomText) String() string {
+	if t.Type != "xhtml" {
+		return t.Text
+	}
+
+	body := strings.TrimSpace(t.Body)
+	start := strings.Index(body, ">")
+	end := strings.LastIndex(body, "</")
+	if !strings.HasPrefix(body, "<") || start < 0 || end <= start {
+		return body
+	}
+	return strings.TrimSpace(body[start+1 : end])
+}
+
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// alternateLink returns the rel="alternate" link, which is also the default
+// when no rel attribute is given.
+func alternateLink(links []AtomLink) string {
+	for _, link := range links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return link.Href
+		}
+	}
+	if len(links) > 0 {
+		return links[0].Href
+	}
+	return ""
+}
+
+func parseAtomFeed(body []byte) (*RSSFeed, error) {
+	atom := &AtomFeed{}
+	if err := xml.Unmarshal(body, atom); err != nil {
+		return nil, err
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Title = atom.Title.String()
+	feed.Channel.Link = alternateLink(atom.Links)
+	feed.Channel.Description = atom.Subtitle.String()
+
+	for _, entry := range atom.Entries {
+		content := entry.Content.String()
+		description := entry.Summary.String()
+		if description == "" {
+			description = content
+		}
+
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Links),
+			Description: description,
+			PubDate:     pubDate,
+			GUID:        entry.ID,
+			Content:     content,
+		})
+	}
+
+	return feed, nil
+}
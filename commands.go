@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type command struct {
+	name  string
+	args  []string
+	flags *flag.FlagSet
+	spec  commandSpec // set by run, so handlers can report usage errors
+}
+
+// argSpec describes one positional argument of a command.
+type argSpec struct {
+	name     string
+	optional bool
+	variadic bool // takes every remaining argument; must be last
+	complete func(*state) []completion
+}
+
+// commandSpec is everything the registry knows about a command: what it does,
+// the arguments and flags it accepts and the handler that runs it.
+type commandSpec struct {
+	name            string
+	description     string
+	hidden          bool // left out of help and completion
+	args            []argSpec
+	flags           func(fs *flag.FlagSet)
+	flagCompletions map[string]func(*state) []completion
+	handler         func(*state, command) error
+	noSetup         bool // runs without a database: no first-run setup or schema check
+	noSchemaCheck   bool // runs against a database whose schema is out of date
+}
+
+type commands struct {
+	handlers map[string]commandSpec
+	order    []string
+}
+
+func (c *commands) register(spec commandSpec) {
+	if _, exists := c.handlers[spec.name]; !exists {
+		c.order = append(c.order, spec.name)
+	}
+	c.handlers[spec.name] = spec
+}
+
+func (c *commands) run(s *state, cmd command) error {
+	spec, exists := c.handlers[cmd.name]
+	if !exists {
+		return fmt.Errorf("unknown command %q, run 'gator help' for a list of commands", cmd.name)
+	}
+
+	fs := spec.flagSet()
+	args, err := parseInterspersed(fs, cmd.args)
+	if errors.Is(err, flag.ErrHelp) {
+		c.printCommandHelp(spec)
+		return nil
+	}
+	if err != nil {
+		return &usageError{spec: spec, message: err.Error()}
+	}
+	if err := spec.checkArgs(args); err != nil {
+		return err
+	}
+
+	cmd.args = args
+	cmd.flags = fs
+	cmd.spec = spec
+	return spec.handler(s, cmd)
+}
+
+// usageError is returned when a command is called with the wrong arguments
+// or flags. main prints the command's usage line along with it.
+type usageError struct {
+	spec    commandSpec
+	message string
+}
+
+func (e *usageError) Error() string {
+	return e.message
+}
+
+// flagSet returns a fresh flag set holding the command's flags. Parse errors
+// are reported through usageError, so the flag package stays quiet.
+func (spec commandSpec) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(spec.name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	if spec.flags != nil {
+		spec.flags(fs)
+	}
+	return fs
+}
+
+func (spec commandSpec) checkArgs(args []string) error {
+	required, variadic := 0, false
+	for _, arg := range spec.args {
+		if !arg.optional {
+			required++
+		}
+		variadic = variadic || arg.variadic
+	}
+
+	switch {
+	case len(args) < required:
+		missing := spec.args[len(args)].name
+		return &usageError{spec: spec, message: fmt.Sprintf("missing argument: %s", missing)}
+	case len(spec.args) == 0 && len(args) > 0:
+		return &usageError{spec: spec, message: fmt.Sprintf("%s takes no arguments", spec.name)}
+	case len(args) > len(spec.args) && !variadic:
+		return &usageError{spec: spec, message: fmt.Sprintf("too many arguments: expected at most %d", len(spec.args))}
+	}
+	return nil
+}
+
+// usage returns the command's synopsis, e.g. "gator browse [flags] [limit]".
+func (spec commandSpec) usage() string {
+	parts := []string{"gator", spec.name}
+	if spec.flags != nil {
+		parts = append(parts, "[flags]")
+	}
+	for _, arg := range spec.args {
+		name := arg.name
+		if arg.variadic {
+			name += "..."
+		}
+		if arg.optional {
+			parts = append(parts, "["+name+"]")
+		} else {
+			parts = append(parts, "<"+name+">")
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (c *commands) handlerHelp(s *state, cmd command) error {
+	if len(cmd.args) == 0 {
+		c.printHelp(os.Stdout)
+		return nil
+	}
+
+	spec, exists := c.handlers[cmd.args[0]]
+	if !exists {
+		return fmt.Errorf("unknown command %q, run 'gator help' for a list of commands", cmd.args[0])
+	}
+	c.printCommandHelp(spec)
+	return nil
+}
+
+// printHelp lists every registered command in registration order.
+func (c *commands) printHelp(w io.Writer) {
+	fmt.Fprintln(w, "Usage: gator [--config <path>] [--profile <name>] [--output text|json|csv|tsv] <command> [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, name := range c.order {
+		if c.handlers[name].hidden {
+			continue
+		}
+		fmt.Fprintf(w, "  %-12s %s\n", name, c.handlers[name].description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'gator help <command>' for details on a command.")
+}
+
+func (c *commands) printCommandHelp(spec commandSpec) {
+	fmt.Printf("Usage: %s\n\n", spec.usage())
+	fmt.Println(spec.description)
+
+	if spec.flags != nil {
+		fmt.Println()
+		fmt.Println("Flags:")
+		fs := spec.flagSet()
+		fs.SetOutput(os.Stdout)
+		fs.PrintDefaults()
+	}
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments, returning the positional ones in order.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// Flag accessors for handlers. The flag must be declared in the command's
+// spec; asking for an undeclared one is a programming error and panics.
+
+func (cmd command) stringFlag(name string) string {
+	return cmd.flags.Lookup(name).Value.String()
+}
+
+func (cmd command) boolFlag(name string) bool {
+	return cmd.flags.Lookup(name).Value.(flag.Getter).Get().(bool)
+}
+
+func (cmd command) intFlag(name string) int {
+	return cmd.flags.Lookup(name).Value.(flag.Getter).Get().(int)
+}
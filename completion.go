@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// completion is one candidate offered to the shell, with an optional
+// description that zsh and fish show next to it.
+type completion struct {
+	value       string
+	description string
+}
+
+const bashCompletion = `# bash completion for gator
+# Load it with: source <(gator completion bash)
+_gator() {
+    local IFS=$'\n'
+    COMPREPLY=($(gator __complete bash "${COMP_LINE:0:COMP_POINT}" 2>/dev/null))
+}
+complete -o default -F _gator gator
+`
+
+const zshCompletion = `#compdef gator
+# zsh completion for gator
+# Load it with: source <(gator completion zsh)
+_gator() {
+    local -a candidates
+    candidates=("${(@f)$(gator __complete zsh "${(j: :)words[1,CURRENT]}" 2>/dev/null)}")
+    if [[ -z "${candidates[1]}" ]]; then
+        _files
+        return
+    fi
+    _describe 'gator' candidates
+}
+compdef _gator gator
+`
+
+const fishCompletion = `# fish completion for gator
+# Load it with: gator completion fish | source
+complete -c gator -f -a '(gator __complete fish (commandline -cp) 2>/dev/null)'
+complete -c gator -n '__fish_seen_subcommand_from import export' -F
+`
+
+var completionScripts = map[string]string{
+	"bash": bashCompletion,
+	"zsh":  zshCompletion,
+	"fish": fishCompletion,
+}
+
+func handlerCompletion(s *state, cmd command) error {
+	script, ok := completionScripts[cmd.args[0]]
+	if !ok {
+		return fmt.Errorf("unsupported shell: %s (expected bash, zsh or fish)", cmd.args[0])
+	}
+
+	fmt.Print(script)
+	return nil
+}
+
+// handlerComplete is called by the completion scripts with the shell name and
+// the command line up to the cursor, and prints the matching candidates in
+// the shell's format. Lookup errors are swallowed: a broken database should
+// mean no suggestions, not noise on the user's prompt.
+func (c *commands) handlerComplete(s *state, cmd command) error {
+	shell, line := cmd.args[0], cmd.args[1]
+
+	words := strings.Fields(line)
+	partial := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		partial = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	if len(words) > 0 {
+		words = words[1:] // the program name
+	}
+
+	for _, candidate := range c.completions(s, words, partial) {
+		if !strings.HasPrefix(candidate.value, partial) {
+			continue
+		}
+		fmt.Println(formatCompletion(shell, candidate, partial))
+	}
+	return nil
+}
+
+// completions works out what can follow words on the command line: global
+// flags and command names first, then the command's own flags and arguments.
+func (c *commands) completions(s *state, words []string, partial string) []completion {
+	var global globalOptions
+	globalFlags := newGlobalFlagSet(&global)
+
+	i, pending := skipFlags(globalFlags, words)
+	if pending != nil {
+		switch pending.Name {
+		case "output":
+			return stringCompletions(outputFormats)
+		case "profile":
+			return completeProfiles(s)
+		}
+		return nil
+	}
+	if i == len(words) {
+		if strings.HasPrefix(partial, "-") {
+			return flagCompletions(globalFlags)
+		}
+		return c.commandCompletions()
+	}
+
+	spec, exists := c.handlers[words[i]]
+	if !exists {
+		return nil
+	}
+	fs := spec.flagSet()
+
+	// Walk the command's arguments to find the position being completed
+	rest := words[i+1:]
+	position := 0
+	for len(rest) > 0 {
+		n, pending := skipFlags(fs, rest)
+		if pending != nil {
+			if complete := spec.flagCompletions[pending.Name]; complete != nil {
+				return complete(s)
+			}
+			return nil
+		}
+		if n == len(rest) {
+			break
+		}
+		position++
+		rest = rest[n+1:]
+	}
+
+	if strings.HasPrefix(partial, "-") {
+		return flagCompletions(fs)
+	}
+	if position >= len(spec.args) {
+		return nil
+	}
+	if complete := spec.args[position].complete; complete != nil {
+		return complete(s)
+	}
+	return nil
+}
+
+// skipFlags returns how many leading words of args are flags and flag values.
+// If the last word is a flag still waiting for its value, that flag is
+// returned too.
+func skipFlags(fs *flag.FlagSet, args []string) (int, *flag.Flag) {
+	i := 0
+	for i < len(args) && strings.HasPrefix(args[i], "-") {
+		name := strings.TrimLeft(args[i], "-")
+		i++
+		if strings.Contains(name, "=") {
+			continue
+		}
+		f := fs.Lookup(name)
+		if f == nil || isBoolFlag(f) {
+			continue
+		}
+		if i == len(args) {
+			return i, f
+		}
+		i++
+	}
+	return i, nil
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func flagCompletions(fs *flag.FlagSet) []completion {
+	var candidates []completion
+	fs.VisitAll(func(f *flag.Flag) {
+		candidates = append(candidates, completion{value: "--" + f.Name, description: f.Usage})
+	})
+	return candidates
+}
+
+func (c *commands) commandCompletions() []completion {
+	var candidates []completion
+	for _, name := range c.order {
+		spec := c.handlers[name]
+		if spec.hidden {
+			continue
+		}
+		candidates = append(candidates, completion{value: name, description: spec.description})
+	}
+	return candidates
+}
+
+func stringCompletions(values []string) []completion {
+	candidates := make([]completion, 0, len(values))
+	for _, value := range values {
+		candidates = append(candidates, completion{value: value})
+	}
+	return candidates
+}
+
+// completionTimeout bounds the database queries behind completions, which run
+// on every Tab press: an unreachable database shouldn't hang the shell.
+const completionTimeout = 2 * time.Second
+
+func completeFeedURLs(s *state) []completion {
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	feeds, err := s.db.GetFeeds(ctx)
+	if err != nil {
+		return nil
+	}
+
+	candidates := make([]completion, 0, len(feeds))
+	for _, feed := range feeds {
+		candidates = append(candidates, completion{value: feed.Url, description: feed.Name})
+	}
+	return candidates
+}
+
+// completeFeeds offers both URLs and names, for flags that accept either.
+func completeFeeds(s *state) []completion {
+	feeds := completeFeedURLs(s)
+	candidates := make([]completion, 0, 2*len(feeds))
+	for _, feed := range feeds {
+		candidates = append(candidates, feed, completion{value: feed.description, description: feed.value})
+	}
+	return candidates
+}
+
+func completeUsernames(s *state) []completion {
+	ctx, cancel := context.WithTimeout(context.Background(), completionTimeout)
+	defer cancel()
+
+	users, err := s.db.GetUsers(ctx)
+	if err != nil {
+		return nil
+	}
+
+	candidates := make([]completion, 0, len(users))
+	for _, user := range users {
+		candidates = append(candidates, completion{value: user.Name})
+	}
+	return candidates
+}
+
+func formatCompletion(shell string, candidate completion, partial string) string {
+	switch shell {
+	case "zsh":
+		value := strings.ReplaceAll(candidate.value, ":", `\:`)
+		if candidate.description == "" {
+			return value
+		}
+		return value + ":" + candidate.description
+	case "fish":
+		if candidate.description == "" {
+			return candidate.value
+		}
+		return candidate.value + "\t" + candidate.description
+	default:
+		// bash splits words on colons, so URLs are completed from the
+		// last colon the user typed
+		if i := strings.LastIndex(partial, ":"); i >= 0 {
+			return candidate.value[i+1:]
+		}
+		return candidate.value
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// monthNames maps lowercased month names and abbreviations in the languages
+// we see in the wild to the English abbreviations time.Parse understands.
+var monthNames = map[string]string{
+	// English
+	"jan": "Jan", "january": "Jan", "feb": "Feb", "february": "Feb",
+	"mar": "Mar", "march": "Mar", "apr": "Apr", "april": "Apr",
+	"may": "May", "jun": "Jun", "june": "Jun", "jul": "Jul", "july": "Jul",
+	"aug": "Aug", "august": "Aug", "sep": "Sep", "sept": "Sep", "september": "Sep",
+	"oct": "Oct", "october": "Oct", "nov": "Nov", "november": "Nov",
+	"dec": "Dec", "december": "Dec",
+	// German
+	"januar": "Jan", "jän": "Jan", "jänner": "Jan", "februar": "Feb",
+	"mär": "Mar", "märz": "Mar", "mrz": "Mar", "mai": "May", "juni": "Jun",
+	"juli": "Jul", "okt": "Oct", "oktober": "Oct", "dez": "Dec", "dezember": "Dec",
+	// French
+	"janv": "Jan", "janvier": "Jan", "févr": "Feb", "fév": "Feb", "février": "Feb",
+	"mars": "Mar", "avr": "Apr", "avril": "Apr", "juin": "Jun", "juil": "Jul",
+	"juillet": "Jul", "août": "Aug", "aout": "Aug", "septembre": "Sep",
+	"octobre": "Oct", "novembre": "Nov", "déc": "Dec", "décembre": "Dec",
+	// Spanish
+	"ene": "Jan", "enero": "Jan", "febrero": "Feb", "marzo": "Mar", "abr": "Apr",
+	"abril": "Apr", "mayo": "May", "junio": "Jun", "julio": "Jul", "ago": "Aug",
+	"agosto": "Aug", "septiembre": "Sep", "setiembre": "Sep", "octubre": "Oct",
+	"noviembre": "Nov", "dic": "Dec", "diciembre": "Dec",
+	// Italian
+	"gen": "Jan", "gennaio": "Jan", "febbraio": "Feb", "aprile": "Apr",
+	"mag": "May", "maggio": "May", "giu": "Jun", "giugno": "Jun", "lug": "Jul",
+	"luglio": "Jul", "set": "Sep", "settembre": "Sep", "ott": "Oct",
+	"ottobre": "Oct", "dicembre": "Dec",
+	// Dutch
+	"januari": "Jan", "februari": "Feb", "maart": "Mar", "mrt": "Mar",
+	"mei": "May", "augustus": "Aug",
+	// Portuguese
+	"janeiro": "Jan", "fev": "Feb", "fevereiro": "Feb", "março": "Mar",
+	"maio": "May", "junho": "Jun", "julho": "Jul", "setembro": "Sep",
+	"out": "Oct", "outubro": "Oct", "dezembro": "Dec",
+}
+
+// zoneOffsets holds the UTC offsets of the timezone abbreviations commonly
+// found in feeds. time.Parse accepts unknown abbreviations but silently
+// treats them as UTC, so the known ones are resolved here instead. Names not
+// listed, including ambiguous ones like IST, are dropped by normalizePubDate
+// and the time is read as UTC; that is off by a few hours at worst, which is
+// better than losing the post.
+var zoneOffsets = map[string]string{
+	"UT": "+0000", "UTC": "+0000", "GMT": "+0000", "Z": "+0000",
+	"EST": "-0500", "EDT": "-0400", "CST": "-0600", "CDT": "-0500",
+	"MST": "-0700", "MDT": "-0600", "PST": "-0800", "PDT": "-0700",
+	"AKST": "-0900", "AKDT": "-0800", "HST": "-1000",
+	"WET": "+0000", "WEST": "+0100", "BST": "+0100", "CET": "+0100",
+	"CEST": "+0200", "MET": "+0100", "MEST": "+0200", "EET": "+0200",
+	"EEST": "+0300", "MSK": "+0300", "JST": "+0900", "KST": "+0900",
+	"AEST": "+1000", "AEDT": "+1100", "NZST": "+1200", "NZDT": "+1300",
+}
+
+// pubDateLayouts are tried in order once a date has been normalised by
+// normalizePubDate.
+var pubDateLayouts = []string{
+	// RFC 822 / RFC 1123 and their common deviations
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 Jan 2006 15:04:05",
+	"2 Jan 2006 15:04",
+	"2 Jan 06 15:04:05",
+	"2 Jan 2006",
+	// RFC 850
+	"2-Jan-2006 15:04:05 -0700",
+	"2-Jan-06 15:04:05 -0700",
+	"2-Jan-06 15:04:05",
+	// US style
+	"Jan 2 2006 15:04:05 -0700",
+	"Jan 2, 2006 15:04:05 -0700",
+	"Jan 2, 2006 15:04:05",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	// ISO 8601
+	time.RFC3339,
+	"2006-01-02T15:04:05-0700",
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	// W3C-DTF, the ISO 8601 profile Dublin Core dates use, also allows
+	// just a month or a year
+	"2006-01",
+	"2006",
+	// Day-first numeric
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+var (
+	dateWordRegexp      = regexp.MustCompile(`\p{L}+\.?`)
+	dateCommentRegexp   = regexp.MustCompile(`\s*\([^)]*\)\s*$`)
+	numericOffsetRegexp = regexp.MustCompile(`^[+-]\d\d:?\d\d$`)
+)
+
+// normalizePubDate rewrites a raw date into a shape pubDateLayouts can
+// parse: month names become English abbreviations, a leading weekday is
+// dropped and a trailing timezone name becomes a numeric offset.
+func normalizePubDate(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	value = dateCommentRegexp.ReplaceAllString(value, "")
+
+	// Drop the filler in Spanish and Portuguese dates ("5 de marzo de 2024")
+	var fields []string
+	for _, field := range strings.Fields(value) {
+		if field != "de" && field != "del" {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// Drop a leading weekday in any language ("Mon,", "Lundi", "Di.,").
+	// Some weekday abbreviations are also month names ("mar." is Tuesday in
+	// French and Spanish), so a word counts as a weekday whenever a month
+	// follows it, either next or after the day of the month.
+	first := strings.TrimRight(fields[0], ",.")
+	if isLetters(first) && len(fields) > 1 {
+		followedByMonth := isMonthName(fields[1]) || (len(fields) > 2 && isMonthName(fields[2]))
+		if !isMonthName(first) || followedByMonth {
+			fields = fields[1:]
+		}
+	}
+
+	for i, field := range fields {
+		fields[i] = dateWordRegexp.ReplaceAllStringFunc(field, func(word string) string {
+			if month, ok := monthNames[strings.ToLower(strings.TrimSuffix(word, "."))]; ok {
+				return month
+			}
+			return word
+		})
+	}
+
+	// Resolve a trailing timezone name. One that follows a numeric offset
+	// ("+0000 GMT") only repeats it, so the offset wins.
+	last := fields[len(fields)-1]
+	if isLetters(last) && len(fields) > 1 {
+		offset, ok := zoneOffsets[strings.ToUpper(last)]
+		if ok && !numericOffsetRegexp.MatchString(fields[len(fields)-2]) {
+			fields[len(fields)-1] = offset
+		} else {
+			fields = fields[:len(fields)-1]
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func isMonthName(word string) bool {
+	_, ok := monthNames[strings.ToLower(strings.TrimRight(word, ",."))]
+	return ok
+}
+
+func isLetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePubDate parses a feed item's publication date, accepting the many
+// variants of RFC 822 and ISO 8601 that publishers actually produce.
+func parsePubDate(value string) (time.Time, error) {
+	normalized := normalizePubDate(value)
+	if normalized == "" {
+		return time.Time{}, fmt.Errorf("missing date")
+	}
+
+	for _, layout := range pubDateLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognised date format: %q", value)
+}
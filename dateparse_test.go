@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePubDate(t *testing.T) {
+	// Dates as they appear in real feeds
+	tests := []struct {
+		value string
+		want  string // RFC 3339, or "" if the date should be rejected
+	}{
+		// RFC 1123 and friends
+		{"Mon, 02 Jan 2006 15:04:05 -0700", "2006-01-02T15:04:05-07:00"},
+		{"Tue, 5 Mar 2024 10:00:00 +0000", "2024-03-05T10:00:00Z"},
+		{"Tue, 05 Mar 2024 10:00:00 GMT", "2024-03-05T10:00:00Z"},
+		{"Wed, 06 Mar 2024 08:30:00 PST", "2024-03-06T08:30:00-08:00"},
+		{"Thu, 07 Mar 2024 09:15:00 EDT", "2024-03-07T09:15:00-04:00"},
+		{"Fri, 08 Mar 2024 12:00 +0100", "2024-03-08T12:00:00+01:00"},
+		{"08 Mar 2024 12:00:00 +0100", "2024-03-08T12:00:00+01:00"},
+		{"Sat, 09 Mar 24 18:45:00 +0000", "2024-03-09T18:45:00Z"},
+		{"Sun, 10 Mar 2024 07:00:00 +01:00", "2024-03-10T07:00:00+01:00"},
+		{"Mon,  11 Mar 2024   06:00:00 +0000", "2024-03-11T06:00:00Z"},
+		{"Tue, 12 Mar 2024 05:00:00 +0000 (UTC)", "2024-03-12T05:00:00Z"},
+		{"Tue, 05 Mar 2024 10:00:00 +0000 GMT", "2024-03-05T10:00:00Z"},
+		{"Tue, 05 Mar 2024 10:00:00 -0500 EST", "2024-03-05T10:00:00-05:00"},
+		{"Tue, 05 Mar 2024 10:00:00 +05:30 IST", "2024-03-05T10:00:00+05:30"},
+		{"Wednesday, 13 March 2024 04:00:00 +0000", "2024-03-13T04:00:00Z"},
+		{"Thu, 14 Mar 2024", "2024-03-14T00:00:00Z"},
+		// RFC 850
+		{"Friday, 15-Mar-24 03:00:00 GMT", "2024-03-15T03:00:00Z"},
+		// US style
+		{"March 16, 2024", "2024-03-16T00:00:00Z"},
+		{"Mar 17 2024", "2024-03-17T00:00:00Z"},
+		{"Mar 18, 2024 02:00:00 -0500", "2024-03-18T02:00:00-05:00"},
+		// ISO 8601
+		{"2024-03-19T01:00:00Z", "2024-03-19T01:00:00Z"},
+		{"2024-03-19T01:00:00.123+02:00", "2024-03-19T01:00:00.123+02:00"},
+		{"2024-03-20T10:20:30+0200", "2024-03-20T10:20:30+02:00"},
+		{"2024-03-21T10:20Z", "2024-03-21T10:20:00Z"},
+		{"2024-03-22 10:20:30", "2024-03-22T10:20:30Z"},
+		{"2024-03-23", "2024-03-23T00:00:00Z"},
+		{"2024-03", "2024-03-01T00:00:00Z"},
+		{"2024", "2024-01-01T00:00:00Z"},
+		// Non-English
+		{"mar., 05 mars 2024 10:00:00 +0100", "2024-03-05T10:00:00+01:00"},
+		{"mar, 05 mar 2024 10:00:00 +0100", "2024-03-05T10:00:00+01:00"},
+		{"lun., 04 mars 2024 09:00:00 +0100", "2024-03-04T09:00:00+01:00"},
+		{"Lundi 4 mars 2024", "2024-03-04T00:00:00Z"},
+		{"5 de marzo de 2024", "2024-03-05T00:00:00Z"},
+		{"Di., 05 Mär. 2024 10:00:00 +0100", "2024-03-05T10:00:00+01:00"},
+		{"Mi, 06 Okt 2021 10:00:00 +0200", "2021-10-06T10:00:00+02:00"},
+		{"sab, 09 ago 2025 14:00:00 +0200", "2025-08-09T14:00:00+02:00"},
+		{"qui, 10 out 2024 08:00:00 -0300", "2024-10-10T08:00:00-03:00"},
+		{"05.03.2024 10:00", "2024-03-05T10:00:00Z"},
+		// Unknown zone names are read as UTC
+		{"Tue, 05 Mar 2024 10:00:00 IST", "2024-03-05T10:00:00Z"},
+		// Not dates
+		{"", ""},
+		{"   ", ""},
+		{"yesterday", ""},
+		{"Mon, 32 Mar 2024 10:00:00 +0000", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parsePubDate(tt.value)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("parsePubDate(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePubDate(%q) returned error: %v", tt.value, err)
+			}
+
+			want, err := time.Parse(time.RFC3339Nano, tt.want)
+			if err != nil {
+				t.Fatalf("bad test value %q: %v", tt.want, err)
+			}
+			if !got.Equal(want) {
+				t.Errorf("parsePubDate(%q) = %v, want %v", tt.value, got, want)
+			}
+			_, gotOffset := got.Zone()
+			_, wantOffset := want.Zone()
+			if gotOffset != wantOffset {
+				t.Errorf("parsePubDate(%q) offset = %d, want %d", tt.value, gotOffset, wantOffset)
+			}
+		})
+	}
+}
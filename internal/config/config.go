@@ -4,76 +4,445 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 )
 
-type Config struct {
+// Profile holds the settings for one database. A config file can hold
+// several, see profiles.go.
+type Profile struct {
 	Db_url            string `json:"db_url"`
 	Current_user_name string `json:"current_user_name"`
+	Fetch_interval    string `json:"fetch_interval,omitempty"`
+	Fetch_concurrency int    `json:"fetch_concurrency,omitempty"`
+}
+
+// Config is the config file plus the profile in use for this run, whose
+// fields are promoted so callers can keep using c.Db_url and friends.
+type Config struct {
+	Profile
+
+	Active_profile string
+	Profiles       map[string]Profile
+
+	// profileName is the profile loaded into Profile: --profile if given,
+	// otherwise the active one
+	profileName string
+
+	// fileValues holds what the config file said for keys that an
+	// environment variable overrode, so WriteConfig doesn't persist them
+	fileValues map[string]string
+
+	// loaded is the file as ReadConfig found it. WriteConfig compares
+	// against it to work out what this run changed.
+	loaded configFile
+}
+
+// configFile is the on-disk layout. db_url and current_user_name at the top
+// level are the format from before profiles and are read into "default".
+type configFile struct {
+	Active_profile    string             `json:"active_profile"`
+	Profiles          map[string]Profile `json:"profiles"`
+	Db_url            string             `json:"db_url,omitempty"`
+	Current_user_name string             `json:"current_user_name,omitempty"`
+}
+
+const defaultProfile = "default"
+
+// fields maps each config key, as named in the JSON file, to its field in a
+// profile.
+var fields = []struct {
+	key string
+	get func(p *Profile) string
+	set func(p *Profile, value string) error
+}{
+	{
+		key: "db_url",
+		get: func(p *Profile) string { return p.Db_url },
+		set: func(p *Profile, value string) error { p.Db_url = value; return nil },
+	},
+	{
+		key: "current_user_name",
+		get: func(p *Profile) string { return p.Current_user_name },
+		set: func(p *Profile, value string) error { p.Current_user_name = value; return nil },
+	},
+	{
+		key: "fetch_interval",
+		get: func(p *Profile) string { return p.Fetch_interval },
+		set: func(p *Profile, value string) error {
+			if value != "" {
+				if _, err := time.ParseDuration(value); err != nil {
+					return fmt.Errorf("invalid fetch_interval: %w", err)
+				}
+			}
+			p.Fetch_interval = value
+			return nil
+		},
+	},
+	{
+		key: "fetch_concurrency",
+		get: func(p *Profile) string {
+			if p.Fetch_concurrency == 0 {
+				return ""
+			}
+			return strconv.Itoa(p.Fetch_concurrency)
+		},
+		set: func(p *Profile, value string) error {
+			if value == "" {
+				p.Fetch_concurrency = 0
+				return nil
+			}
+			concurrency, err := strconv.Atoi(value)
+			if err != nil || concurrency < 1 {
+				return fmt.Errorf("invalid fetch_concurrency: %s", value)
+			}
+			p.Fetch_concurrency = concurrency
+			return nil
+		},
+	},
+}
+
+// envOverrides maps environment variables onto the config keys they
+// override. They take precedence over the config file.
+var envOverrides = []struct {
+	name string
+	key  string
+}{
+	{"GATOR_DB_URL", "db_url"},
+	{"GATOR_USER", "current_user_name"},
+	{"GATOR_FETCH_INTERVAL", "fetch_interval"},
+	{"GATOR_FETCH_CONCURRENCY", "fetch_concurrency"},
 }
 
-func getConfigPath() (string, error) {
+// Keys returns the names of all config keys.
+func Keys() []string {
+	keys := make([]string, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, f.key)
+	}
+	return keys
+}
+
+// Get returns the value of a config key in the profile in use, including any
+// environment override.
+func (c *Config) Get(key string) (string, error) {
+	return getField(&c.Profile, key)
+}
+
+// Set changes the value of a config key in the profile in use. Use
+// WriteConfig to save it.
+func (c *Config) Set(key, value string) error {
+	return setField(&c.Profile, key, value)
+}
+
+func getField(p *Profile, key string) (string, error) {
+	for _, f := range fields {
+		if f.key == key {
+			return f.get(p), nil
+		}
+	}
+	return "", fmt.Errorf("unknown config key: %s", key)
+}
+
+func setField(p *Profile, key, value string) error {
+	for _, f := range fields {
+		if f.key == key {
+			return f.set(p, value)
+		}
+	}
+	return fmt.Errorf("unknown config key: %s", key)
+}
+
+// OverriddenBy returns the environment variable currently overriding key,
+// or "" if there is none.
+func OverriddenBy(key string) string {
+	for _, override := range envOverrides {
+		if _, ok := os.LookupEnv(override.name); ok && override.key == key {
+			return override.name
+		}
+	}
+	return ""
+}
+
+// configPath is set by SetPath from the --config flag.
+var configPath string
+
+// selectedProfile is set by SetProfile from the --profile flag.
+var selectedProfile string
+
+// SetPath makes ReadConfig and WriteConfig use the file at path instead of
+// the default location.
+func SetPath(path string) {
+	configPath = path
+}
+
+// Path returns the config file location: the --config flag, then the
+// GATOR_CONFIG environment variable, then $XDG_CONFIG_HOME/gator/config.json.
+func Path() (string, error) {
+	if configPath != "" {
+		return configPath, nil
+	}
+	if path := os.Getenv("GATOR_CONFIG"); path != "" {
+		return path, nil
+	}
+	return defaultConfigPath()
+}
+
+func defaultConfigPath() (string, error) {
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "gator", "config.json"), nil
+}
+
+func legacyConfigPath() (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
 	}
-	return homeDir + "/.gatorconfig.json", nil
+	return filepath.Join(homeDir, ".gatorconfig.json"), nil
+}
+
+// migrateLegacyConfig moves ~/.gatorconfig.json to the default location if
+// only the old file exists.
+func migrateLegacyConfig(path string) error {
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		return nil
+	}
+
+	legacyPath, err := legacyConfigPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(legacyPath); err != nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("couldn't create config directory: %w", err)
+	}
+	if err := os.Rename(legacyPath, path); err != nil {
+		return fmt.Errorf("couldn't move %s to %s: %w", legacyPath, path, err)
+	}
+	// The old file was created world-readable
+	return os.Chmod(path, 0600)
 }
 
 func ReadConfig(c *Config) error {
-	configPath, err := getConfigPath()
+	configPath, err := Path()
 	if err != nil {
 		return err
 	}
 
-	// Try to open the file
-	file, err := os.Open(configPath)
+	defaultPath, err := defaultConfigPath()
 	if err != nil {
-		// If the file doesn't exist, create it with default values
-		if os.IsNotExist(err) {
-			// Initialize with default values
-			c.Db_url = ""
-			c.Current_user_name = ""
-			// Write the default config
-			return WriteConfig(c)
-		}
 		return err
 	}
-	defer file.Close()
+	if configPath == defaultPath {
+		if err := migrateLegacyConfig(configPath); err != nil {
+			return err
+		}
+	}
 
-	// If file exists, decode it
-	err = json.NewDecoder(file).Decode(c)
+	file, err := readConfigFile(configPath)
 	if err != nil {
 		return err
 	}
 
+	c.loaded = file
+	c.Active_profile = file.Active_profile
+	c.Profiles = make(map[string]Profile, len(file.Profiles))
+	for name, profile := range file.Profiles {
+		c.Profiles[name] = profile
+	}
+
+	c.profileName = selectedProfile
+	if c.profileName == "" {
+		c.profileName = os.Getenv("GATOR_PROFILE")
+	}
+	if c.profileName == "" {
+		c.profileName = c.Active_profile
+	}
+
+	profile, ok := c.Profiles[c.profileName]
+	if !ok && c.profileName != c.Active_profile {
+		return fmt.Errorf("no profile named %q in %s", c.profileName, configPath)
+	}
+	c.Profile = profile
+
+	c.fileValues = make(map[string]string)
+	for _, override := range envOverrides {
+		if value, ok := os.LookupEnv(override.name); ok {
+			original, err := getField(&c.Profile, override.key)
+			if err != nil {
+				return err
+			}
+			if err := setField(&c.Profile, override.key, value); err != nil {
+				return fmt.Errorf("%s: %w", override.name, err)
+			}
+			c.fileValues[override.key] = original
+		}
+	}
+
 	return nil
 }
 
+// readConfigFile reads and decodes the config file, converting the format
+// from before profiles.
+func readConfigFile(path string) (configFile, error) {
+	file := configFile{}
+
+	// Try to open the file
+	f, err := os.Open(path)
+	if err != nil {
+		// A missing file just means defaults, so gator can run on
+		// environment variables alone
+		if !os.IsNotExist(err) {
+			return file, err
+		}
+	} else {
+		defer f.Close()
+
+		// If file exists, decode it
+		if err := json.NewDecoder(f).Decode(&file); err != nil {
+			return file, fmt.Errorf("couldn't parse %s: %w", path, err)
+		}
+	}
+
+	if file.Profiles == nil {
+		file.Profiles = make(map[string]Profile)
+	}
+	if len(file.Profiles) == 0 && (file.Db_url != "" || file.Current_user_name != "") {
+		file.Profiles[defaultProfile] = Profile{Db_url: file.Db_url, Current_user_name: file.Current_user_name}
+	}
+	file.Db_url, file.Current_user_name = "", ""
+	if file.Active_profile == "" {
+		file.Active_profile = defaultProfile
+	}
+	return file, nil
+}
+
+// mergeChanges applies the changes made in this run, relative to the file as
+// it was loaded, onto the file as it is now. Another gator may have saved in
+// between, and its changes to other keys and profiles must survive.
+func (c *Config) mergeChanges(current configFile, changed map[string]Profile) configFile {
+	if c.Active_profile != c.loaded.Active_profile {
+		current.Active_profile = c.Active_profile
+	}
+
+	for name := range c.loaded.Profiles {
+		if _, ok := changed[name]; !ok {
+			delete(current.Profiles, name) // removed in this run
+		}
+	}
+
+	for name, after := range changed {
+		// A profile this run created compares against the zero profile, so
+		// one created concurrently elsewhere keeps its other fields
+		before, existed := c.loaded.Profiles[name]
+		profile, exists := current.Profiles[name]
+		if existed && !exists && after == before {
+			continue // removed elsewhere and untouched here
+		}
+		if !exists {
+			current.Profiles[name] = after
+			continue
+		}
+		for _, f := range fields {
+			if value := f.get(&after); value != f.get(&before) {
+				f.set(&profile, value)
+			}
+		}
+		current.Profiles[name] = profile
+	}
+
+	return current
+}
+
 func WriteConfig(c *Config) error {
-	configPath, err := getConfigPath()
+	configPath, err := Path()
 	if err != nil {
 		return err
 	}
 
-	file, err := os.OpenFile(configPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	// Keep the file's own values for fields still coming from the environment
+	profile := c.Profile
+	for _, override := range envOverrides {
+		original, ok := c.fileValues[override.key]
+		if !ok {
+			continue
+		}
+		value, err := getField(&profile, override.key)
+		if err != nil {
+			return err
+		}
+		if value == os.Getenv(override.name) {
+			if err := setField(&profile, override.key, original); err != nil {
+				return err
+			}
+		}
+	}
+
+	changed := make(map[string]Profile, len(c.Profiles)+1)
+	for name, p := range c.Profiles {
+		changed[name] = p
+	}
+	changed[c.profileName] = profile
+
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return fmt.Errorf("couldn't create config directory: %w", err)
+	}
+
+	// Serialise writers, and merge into the file as it is now rather than
+	// as it was at startup, so concurrent gator invocations don't undo each
+	// other's changes
+	unlock, err := lockFile(configPath + ".lock")
+	if err != nil {
+		return fmt.Errorf("couldn't lock config file: %w", err)
+	}
+	defer unlock()
+
+	current, err := readConfigFile(configPath)
 	if err != nil {
-		fmt.Println("Error:", err)
 		return err
 	}
-	fmt.Println("read")
+	file := c.mergeChanges(current, changed)
 
-	defer file.Close()
+	// Write a temp file next to the config and rename it into place, so a
+	// crash never leaves a truncated config behind. CreateTemp uses 0600,
+	// which matters because the file holds database credentials.
+	tmp, err := os.CreateTemp(filepath.Dir(configPath), ".config-*.tmp")
+	if err != nil {
+		return fmt.Errorf("couldn't create temp config file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
 
-	encoder := json.NewEncoder(file)
+	encoder := json.NewEncoder(tmp)
 	encoder.SetIndent("", "  ") // Pretty print JSON with indentation
 
 	// Write the struct as JSON
-	err = encoder.Encode(c)
-	if err != nil {
+	if err := encoder.Encode(file); err != nil {
+		tmp.Close()
 		return fmt.Errorf("error encoding JSON: %w", err)
 	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("couldn't write config file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("couldn't write config file: %w", err)
+	}
 
-	fmt.Println("Config written to file successfully")
+	if err := os.Rename(tmp.Name(), configPath); err != nil {
+		return fmt.Errorf("couldn't replace config file: %w", err)
+	}
 
+	c.loaded = file
 	return nil
 }
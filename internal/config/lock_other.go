@@ -0,0 +1,9 @@
+//go:build !unix
+
+package configGator
+
+// lockFile is a no-op where flock isn't available; writes are still atomic
+// thanks to the rename in WriteConfig.
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,77 @@
+package configGator
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SetProfile makes ReadConfig load the named profile instead of the active
+// one, for this run only.
+func SetProfile(name string) {
+	selectedProfile = name
+}
+
+// ProfileName returns the name of the profile in use.
+func (c *Config) ProfileName() string {
+	return c.profileName
+}
+
+// ProfileNames returns the names of all profiles, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles)+1)
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	// The profile in use exists even before it is first written
+	if _, ok := c.Profiles[c.profileName]; !ok {
+		names = append(names, c.profileName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetProfile returns a saved profile, or the one in use, by name.
+func (c *Config) GetProfile(name string) (Profile, bool) {
+	if name == c.profileName {
+		return c.Profile, true
+	}
+	profile, ok := c.Profiles[name]
+	return profile, ok
+}
+
+// UseProfile makes name the active profile for future runs.
+func (c *Config) UseProfile(name string) error {
+	if _, ok := c.GetProfile(name); !ok {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	c.Active_profile = name
+	return nil
+}
+
+// AddProfile saves a new profile.
+func (c *Config) AddProfile(name string, profile Profile) error {
+	if name == "" {
+		return fmt.Errorf("profile name can't be empty")
+	}
+	if _, ok := c.GetProfile(name); ok {
+		return fmt.Errorf("profile %q already exists", name)
+	}
+	c.Profiles[name] = profile
+	return nil
+}
+
+// RemoveProfile deletes a profile. The active profile and the one in use
+// can't be removed.
+func (c *Config) RemoveProfile(name string) error {
+	if _, ok := c.GetProfile(name); !ok {
+		return fmt.Errorf("no profile named %q", name)
+	}
+	if name == c.Active_profile {
+		return fmt.Errorf("can't remove the active profile %q: switch to another one first", name)
+	}
+	if name == c.profileName {
+		return fmt.Errorf("can't remove profile %q while using it", name)
+	}
+	delete(c.Profiles, name)
+	return nil
+}
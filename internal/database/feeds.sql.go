@@ -7,6 +7,7 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,7 +23,7 @@ VALUES (
     $5,
     $6
 )
-RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_attempt_at, site_url, claimed_until, last_success_at
 `
 
 type CreateFeedParams struct {
@@ -52,12 +53,20 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.SiteUrl,
+		&i.ClaimedUntil,
+		&i.LastSuccessAt,
 	)
 	return i, err
 }
 
 const getFeedByURL = `-- name: GetFeedByURL :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at FROM feeds WHERE url = $1
+SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_attempt_at, site_url, claimed_until, last_success_at FROM feeds WHERE url = $1
 `
 
 func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
@@ -71,6 +80,14 @@ func (q *Queries) GetFeedByURL(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.ConsecutiveFailures,
+		&i.LastError,
+		&i.NextAttemptAt,
+		&i.SiteUrl,
+		&i.ClaimedUntil,
+		&i.LastSuccessAt,
 	)
 	return i, err
 }
@@ -109,3 +126,19 @@ func (q *Queries) GetFeeds(ctx context.Context) ([]GetFeedsRow, error) {
 	}
 	return items, nil
 }
+
+const setFeedSiteURL = `-- name: SetFeedSiteURL :exec
+UPDATE feeds
+SET site_url = $1
+WHERE id = $2
+`
+
+type SetFeedSiteURLParams struct {
+	SiteUrl sql.NullString
+	ID      uuid.UUID
+}
+
+func (q *Queries) SetFeedSiteURL(ctx context.Context, arg SetFeedSiteURLParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedSiteURL, arg.SiteUrl, arg.ID)
+	return err
+}
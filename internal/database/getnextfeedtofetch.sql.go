@@ -7,26 +7,78 @@ package database
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const claimNextFeedsToFetch = `-- name: ClaimNextFeedsToFetch :many
+UPDATE feeds
+SET claimed_until = $1
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE (next_attempt_at IS NULL OR next_attempt_at <= $2)
+      AND (claimed_until IS NULL OR claimed_until < $2)
+    ORDER BY last_fetched_at NULLS FIRST
+    LIMIT $3
+    FOR UPDATE SKIP LOCKED
 )
+RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, etag, last_modified, consecutive_failures, last_error, next_attempt_at, site_url, claimed_until, last_success_at
+`
+
+type ClaimNextFeedsToFetchParams struct {
+	ClaimedUntil sql.NullTime
+	Now          sql.NullTime
+	MaxResults   int32
+}
+
+func (q *Queries) ClaimNextFeedsToFetch(ctx context.Context, arg ClaimNextFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimNextFeedsToFetch, arg.ClaimedUntil, arg.Now, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.ConsecutiveFailures,
+			&i.LastError,
+			&i.NextAttemptAt,
+			&i.SiteUrl,
+			&i.ClaimedUntil,
+			&i.LastSuccessAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
-SELECT id, created_at, updated_at, name, url, user_id, last_fetched_at 
-FROM feeds
-ORDER BY last_fetched_at NULLS FIRST
-LIMIT 1
+const releaseFeedClaim = `-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_until = NULL
+WHERE id = $1
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
-	var i Feed
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Name,
-		&i.Url,
-		&i.UserID,
-		&i.LastFetchedAt,
-	)
-	return i, err
+func (q *Queries) ReleaseFeedClaim(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, releaseFeedClaim, id)
+	return err
 }
@@ -15,17 +15,26 @@ import (
 
 const markFeedFetched = `-- name: MarkFeedFetched :exec
 UPDATE feeds
-SET last_fetched_at = $1, updated_at = $2
-WHERE id = $3
+SET last_fetched_at = $1, last_success_at = $1, updated_at = $2, etag = $3, last_modified = $4,
+    consecutive_failures = 0, last_error = NULL, next_attempt_at = NULL, claimed_until = NULL
+WHERE id = $5
 `
 
 type MarkFeedFetchedParams struct {
 	LastFetchedAt sql.NullTime
 	UpdatedAt     time.Time
+	Etag          sql.NullString
+	LastModified  sql.NullString
 	ID            uuid.UUID
 }
 
 func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.LastFetchedAt, arg.UpdatedAt, arg.ID)
+	_, err := q.db.ExecContext(ctx, markFeedFetched,
+		arg.LastFetchedAt,
+		arg.UpdatedAt,
+		arg.Etag,
+		arg.LastModified,
+		arg.ID,
+	)
 	return err
 }
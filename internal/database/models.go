@@ -12,13 +12,30 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	ConsecutiveFailures int32
+	LastError           sql.NullString
+	NextAttemptAt       sql.NullTime
+	SiteUrl             sql.NullString
+	ClaimedUntil        sql.NullTime
+	LastSuccessAt       sql.NullTime
+}
+
+type FeedFetch struct {
+	ID         int32
+	FeedID     uuid.UUID
+	FetchedAt  time.Time
+	StatusCode sql.NullInt32
+	ItemCount  int32
+	Error      sql.NullString
 }
 
 type FeedFollow struct {
@@ -27,17 +44,42 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Folder    sql.NullString
 }
 
 type Post struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Title        string
+	Url          string
+	Description  sql.NullString
+	PublishedAt  time.Time
+	FeedID       uuid.UUID
+	Guid         string
+	Content      sql.NullString
+	SearchVector interface{}
+}
+
+type PostRead struct {
+	UserID uuid.UUID
+	PostID uuid.UUID
+	ReadAt time.Time
+}
+
+type PostRevision struct {
+	ID          int32
+	PostID      uuid.UUID
+	RevisedAt   time.Time
 	Title       string
-	Url         string
 	Description sql.NullString
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	Content     sql.NullString
+}
+
+type PostStar struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	StarredAt time.Time
 }
 
 type User struct {
@@ -13,22 +13,22 @@ import (
 	"github.com/google/uuid"
 )
 
-const createPost = `-- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
-VALUES (
-    $1,
-    $2,
-    $3,
-    $4,
-    $5,
-    $6,
-    $7,
-    $8
-)
-RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+const adoptLegacyPostGUID = `-- name: AdoptLegacyPostGUID :one
+UPDATE posts
+SET guid = $1
+WHERE feed_id = $2
+  AND url = $3
+  AND (guid = $3 OR guid LIKE 'sha256:%')
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content
 `
 
-type CreatePostParams struct {
+type AdoptLegacyPostGUIDParams struct {
+	Guid   string
+	FeedID uuid.UUID
+	Url    string
+}
+
+type AdoptLegacyPostGUIDRow struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
@@ -37,20 +37,15 @@ type CreatePostParams struct {
 	Description sql.NullString
 	PublishedAt time.Time
 	FeedID      uuid.UUID
+	Guid        string
+	Content     sql.NullString
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
-	row := q.db.QueryRowContext(ctx, createPost,
-		arg.ID,
-		arg.CreatedAt,
-		arg.UpdatedAt,
-		arg.Title,
-		arg.Url,
-		arg.Description,
-		arg.PublishedAt,
-		arg.FeedID,
-	)
-	var i Post
+// Posts stored before guids existed had their guid backfilled from the url,
+// and guid-less posts were once keyed by a hash of their content.
+func (q *Queries) AdoptLegacyPostGUID(ctx context.Context, arg AdoptLegacyPostGUIDParams) (AdoptLegacyPostGUIDRow, error) {
+	row := q.db.QueryRowContext(ctx, adoptLegacyPostGUID, arg.Guid, arg.FeedID, arg.Url)
+	var i AdoptLegacyPostGUIDRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
@@ -60,34 +55,159 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		&i.Description,
 		&i.PublishedAt,
 		&i.FeedID,
+		&i.Guid,
+		&i.Content,
 	)
 	return i, err
 }
 
-const getPostsForUser = `-- name: GetPostsForUser :many
-SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id
+const browsePostsNewest = `-- name: BrowsePostsNewest :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = $1
+  AND ($2::boolean OR post_reads.post_id IS NULL)
+  AND ($3::text IS NULL OR feeds.url = $3::text OR feeds.name = $3::text)
+  AND ($4::timestamp IS NULL OR posts.published_at >= $4::timestamp)
+  AND ($5::timestamp IS NULL OR posts.published_at < $5::timestamp)
+  AND (
+      $6::timestamp IS NULL
+      OR (posts.published_at, posts.id) < ($6::timestamp, $7::uuid)
+  )
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT $8
+`
+
+type BrowsePostsNewestParams struct {
+	UserID            uuid.UUID
+	IncludeRead       bool
+	Feed              sql.NullString
+	Since             sql.NullTime
+	Until             sql.NullTime
+	CursorPublishedAt sql.NullTime
+	CursorID          uuid.NullUUID
+	MaxResults        int32
+}
+
+type BrowsePostsNewestRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Guid        string
+	Content     sql.NullString
+}
+
+func (q *Queries) BrowsePostsNewest(ctx context.Context, arg BrowsePostsNewestParams) ([]BrowsePostsNewestRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsNewest,
+		arg.UserID,
+		arg.IncludeRead,
+		arg.Feed,
+		arg.Since,
+		arg.Until,
+		arg.CursorPublishedAt,
+		arg.CursorID,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsNewestRow
+	for rows.Next() {
+		var i BrowsePostsNewestRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.Content,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const browsePostsOldest = `-- name: BrowsePostsOldest :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
 WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2
+  AND ($2::boolean OR post_reads.post_id IS NULL)
+  AND ($3::text IS NULL OR feeds.url = $3::text OR feeds.name = $3::text)
+  AND ($4::timestamp IS NULL OR posts.published_at >= $4::timestamp)
+  AND ($5::timestamp IS NULL OR posts.published_at < $5::timestamp)
+  AND (
+      $6::timestamp IS NULL
+      OR (posts.published_at, posts.id) > ($6::timestamp, $7::uuid)
+  )
+ORDER BY posts.published_at ASC, posts.id ASC
+LIMIT $8
 `
 
-type GetPostsForUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
+type BrowsePostsOldestParams struct {
+	UserID            uuid.UUID
+	IncludeRead       bool
+	Feed              sql.NullString
+	Since             sql.NullTime
+	Until             sql.NullTime
+	CursorPublishedAt sql.NullTime
+	CursorID          uuid.NullUUID
+	MaxResults        int32
+}
+
+type BrowsePostsOldestRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Guid        string
+	Content     sql.NullString
 }
 
-func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams) ([]Post, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsForUser, arg.UserID, arg.Limit)
+func (q *Queries) BrowsePostsOldest(ctx context.Context, arg BrowsePostsOldestParams) ([]BrowsePostsOldestRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsOldest,
+		arg.UserID,
+		arg.IncludeRead,
+		arg.Feed,
+		arg.Since,
+		arg.Until,
+		arg.CursorPublishedAt,
+		arg.CursorID,
+		arg.MaxResults,
+	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Post
+	var items []BrowsePostsOldestRow
 	for rows.Next() {
-		var i Post
+		var i BrowsePostsOldestRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
@@ -97,6 +217,8 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 			&i.Description,
 			&i.PublishedAt,
 			&i.FeedID,
+			&i.Guid,
+			&i.Content,
 		); err != nil {
 			return nil, err
 		}
@@ -110,3 +232,103 @@ func (q *Queries) GetPostsForUser(ctx context.Context, arg GetPostsForUserParams
 	}
 	return items, nil
 }
+
+const getPostByFeedAndGUID = `-- name: GetPostByFeedAndGUID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content
+FROM posts
+WHERE feed_id = $1 AND guid = $2
+`
+
+type GetPostByFeedAndGUIDParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+type GetPostByFeedAndGUIDRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Guid        string
+	Content     sql.NullString
+}
+
+func (q *Queries) GetPostByFeedAndGUID(ctx context.Context, arg GetPostByFeedAndGUIDParams) (GetPostByFeedAndGUIDRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostByFeedAndGUID, arg.FeedID, arg.Guid)
+	var i GetPostByFeedAndGUIDRow
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Title,
+		&i.Url,
+		&i.Description,
+		&i.PublishedAt,
+		&i.FeedID,
+		&i.Guid,
+		&i.Content,
+	)
+	return i, err
+}
+
+const upsertPost = `-- name: UpsertPost :execrows
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10
+)
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+   OR posts.description IS DISTINCT FROM EXCLUDED.description
+   OR posts.content IS DISTINCT FROM EXCLUDED.content
+   OR posts.url IS DISTINCT FROM EXCLUDED.url
+`
+
+type UpsertPostParams struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Title       string
+	Url         string
+	Description sql.NullString
+	PublishedAt time.Time
+	FeedID      uuid.UUID
+	Guid        string
+	Content     sql.NullString
+}
+
+func (q *Queries) UpsertPost(ctx context.Context, arg UpsertPostParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, upsertPost,
+		arg.ID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.PublishedAt,
+		arg.FeedID,
+		arg.Guid,
+		arg.Content,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
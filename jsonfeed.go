@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"strings"
+)
+
+type JSONFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	Description string         `json:"description"`
+	Items       []JSONFeedItem `json:"items"`
+}
+
+type JSONFeedItem struct {
+	ID            jsonFeedID `json:"id"`
+	URL           string     `json:"url"`
+	Title         string     `json:"title"`
+	ContentHTML   string     `json:"content_html"`
+	ContentText   string     `json:"content_text"`
+	Summary       string     `json:"summary"`
+	DatePublished string     `json:"date_published"`
+	DateModified  string     `json:"date_modified"`
+}
+
+// jsonFeedID is an item id. The spec says it is a string but readers must
+// accept other types, such as numbers, and treat them as strings.
+type jsonFeedID string
+
+func (id *jsonFeedID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = jsonFeedID(s)
+		return nil
+	}
+	if string(data) == "null" {
+		*id = ""
+		return nil
+	}
+	*id = jsonFeedID(data)
+	return nil
+}
+
+// isJSONFeed reports whether a response looks like a JSON Feed. Publishers
+// often mislabel feeds, so the first byte of the body decides: "{" is JSON
+// and "<" is XML. The Content-Type header is only consulted when the body
+// starts with neither.
+func isJSONFeed(contentType string, body []byte) bool {
+	body = bytes.TrimSpace(body)
+	switch {
+	case bytes.HasPrefix(body, []byte("{")):
+		return true
+	case bytes.HasPrefix(body, []byte("<")):
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/feed+json" || mediaType == "application/json")
+}
+
+func parseJSONFeed(body []byte) (*RSSFeed, error) {
+	jsonFeed := &JSONFeed{}
+	if err := json.Unmarshal(body, jsonFeed); err != nil {
+		return nil, err
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Title = jsonFeed.Title
+	feed.Channel.Link = jsonFeed.HomePageURL
+	feed.Channel.Description = jsonFeed.Description
+
+	for _, item := range jsonFeed.Items {
+		description := item.Summary
+		if description == "" {
+			description = item.ContentHTML
+		}
+		if description == "" {
+			description = item.ContentText
+		}
+
+		content := item.ContentHTML
+		if content == "" {
+			content = item.ContentText
+		}
+
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+
+		// JSON Feed ids may be any string; fall back to the url like RSS readers do
+		guid := strings.TrimSpace(string(item.ID))
+		if guid == "" {
+			guid = item.URL
+		}
+
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       item.Title,
+			Link:        item.URL,
+			Description: description,
+			PubDate:     pubDate,
+			GUID:        guid,
+			Content:     content,
+		})
+	}
+
+	return feed, nil
+}
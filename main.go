@@ -4,9 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,45 +25,20 @@ import (
 
 type state struct {
 	db         *database.Queries
+	conn       *sql.DB
 	configFile *configGator.Config
-}
-
-type command struct {
-	name string
-	args []string
-}
-
-type commands struct {
-	handlers map[string]func(*state, command) error
-}
-
-func (c *commands) register(name string, f func(*state, command) error) {
-	c.handlers[name] = f
-}
-
-func (c *commands) run(s *state, cmd command) error {
-	if handlerFunc, exists := c.handlers[cmd.name]; exists {
-		err := handlerFunc(s, cmd)
-		return err
-	} else {
-		return errors.New("Function command doesn't exist")
-	}
+	out        *renderer
 }
 
 func handlerLogin(s *state, cmd command) error {
-	if len(cmd.args) == 0 {
-		return errors.New("missing argument for login")
-	}
-
 	username := cmd.args[0]
 
 	// Try to get the user
-	_, err := s.db.GetUser(context.Background(), username)
+	user, err := s.db.GetUser(context.Background(), username)
 	if err != nil {
 		// If no rows found, user doesn't exist
 		if err == sql.ErrNoRows {
-			fmt.Printf("User %s does not exist\n", username)
-			os.Exit(1)
+			return fmt.Errorf("user %s does not exist", username)
 		}
 		return err
 	}
@@ -70,15 +50,13 @@ func handlerLogin(s *state, cmd command) error {
 		return err
 	}
 
-	fmt.Printf("The user %s has been set\n", cmd.args[0])
-
-	return nil
+	return s.out.render(newUserOutput(user, username), func() error {
+		fmt.Printf("The user %s has been set\n", cmd.args[0])
+		return nil
+	})
 }
 
 func handlerRegister(s *state, cmd command) error {
-	if len(cmd.args) == 0 {
-		return errors.New("username required")
-	}
 	username := cmd.args[0]
 
 	user, err := s.db.CreateUser(
@@ -94,8 +72,7 @@ func handlerRegister(s *state, cmd command) error {
 	if err != nil {
 		// Check if this is a unique violation error
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			fmt.Printf("User %s already exists\n", username)
-			os.Exit(1)
+			return fmt.Errorf("user %s already exists", username)
 		}
 		return err
 	}
@@ -107,79 +84,118 @@ func handlerRegister(s *state, cmd command) error {
 		return err
 	}
 
-	// User-friendly message
-	fmt.Printf("User %s successfully created!\n", username)
-
-	// Debug information
-	fmt.Printf("User details: %+v\n", user)
+	return s.out.render(newUserOutput(user, username), func() error {
+		// User-friendly message
+		fmt.Printf("User %s successfully created!\n", username)
 
-	return nil
+		// Debug information
+		fmt.Printf("User details: %+v\n", user)
+		return nil
+	})
 }
 
 func handlerResetUsers(s *state, cmd command) error {
 	err := s.db.ResetUsers(context.Background())
 	if err != nil {
-		fmt.Println("Failed to reset users", err)
-		return err
+		return fmt.Errorf("couldn't reset users: %v", err)
 	}
 
 	s.configFile.Current_user_name = ""
 
-	return nil
+	return s.out.render(countOutput{Action: "reset", Count: 0}, func() error {
+		fmt.Println("All users have been reset")
+		return nil
+	})
 }
 
 func handlerGetUsers(s *state, cmd command) error {
 	users, err := s.db.GetUsers(context.Background())
 	if err != nil {
-		fmt.Println("Failed to get users", err)
-		return err
+		return fmt.Errorf("couldn't get users: %v", err)
 	}
 
-	for user := range users {
-		if users[user].Name == s.configFile.Current_user_name {
-			fmt.Printf("* %s (current)\n", users[user].Name)
-		} else {
-			fmt.Printf("* %s\n", users[user].Name)
-		}
+	out := make([]userOutput, 0, len(users))
+	for _, user := range users {
+		out = append(out, newUserOutput(user, s.configFile.Current_user_name))
 	}
 
-	return nil
+	return s.out.render(out, func() error {
+		for user := range users {
+			if users[user].Name == s.configFile.Current_user_name {
+				fmt.Printf("* %s (current)\n", users[user].Name)
+			} else {
+				fmt.Printf("* %s\n", users[user].Name)
+			}
+		}
+		return nil
+	})
 }
 
 func handlerAgg(s *state, cmd command) error {
-	ctx := context.Background()
+	// Cancel in-flight fetches on Ctrl+C or a service stop
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Parse the interval from the command argument (e.g., "1m", "30s")
-	if len(cmd.args) == 0 {
-		return fmt.Errorf("time interval argument is required")
+	// Parse the interval from the command argument (e.g., "1m", "30s"),
+	// falling back to the profile's fetch_interval
+	interval := s.configFile.Fetch_interval
+	if len(cmd.args) > 0 {
+		interval = cmd.args[0]
+	}
+	if interval == "" {
+		return fmt.Errorf("time interval argument is required (or set fetch_interval with gator config set)")
 	}
-	timeBetweenRequests, err := time.ParseDuration(cmd.args[0])
+	timeBetweenRequests, err := time.ParseDuration(interval)
 	if err != nil {
 		return fmt.Errorf("invalid time duration: %v", err)
 	}
 
-	fmt.Printf("Collecting feeds every %s\n", timeBetweenRequests)
+	// Optional number of feeds to fetch in parallel per tick
+	concurrency := 1
+	if s.configFile.Fetch_concurrency > 0 {
+		concurrency = s.configFile.Fetch_concurrency
+	}
+	if len(cmd.args) > 1 {
+		concurrency, err = strconv.Atoi(cmd.args[1])
+		if err != nil || concurrency < 1 {
+			return fmt.Errorf("invalid concurrency: %s", cmd.args[1])
+		}
+	}
+
+	fmt.Printf("Collecting %d feed(s) every %s\n", concurrency, timeBetweenRequests)
 
 	// Set up a ticker to scrape feeds periodically
 	ticker := time.NewTicker(timeBetweenRequests)
 	defer ticker.Stop()
 
+	var total scrapeStats
+	var lastPruned time.Time
+
 	for {
 		// Call scrapeFeeds every time the ticker ticks
-		if err := scrapeFeeds(ctx, s); err != nil {
+		stats, err := scrapeFeeds(ctx, s, concurrency)
+		total.add(stats)
+		if err != nil && ctx.Err() == nil {
 			log.Printf("Error during feed scraping: %v", err)
 		}
+		// Trimming the fetch history once a day is plenty
+		if ctx.Err() == nil && time.Since(lastPruned) >= 24*time.Hour {
+			pruneFeedFetches(ctx, s)
+			lastPruned = time.Now()
+		}
 
-		// Wait for the next tick or allow for a Ctrl+C to stop the loop
-		<-ticker.C
+		// Wait for the next tick or stop on Ctrl+C
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			fmt.Printf("\nStopped: fetched %d feed(s), %d failed, %d new post(s), %d updated post(s)\n",
+				total.FeedsFetched, total.FeedsFailed, total.PostsCreated, total.PostsUpdated)
+			return nil
+		}
 	}
 }
 
 func handlerAddFeed(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 2 {
-		return fmt.Errorf("expected 2 arguments: name and url")
-	}
-
 	name := cmd.args[0]
 	url := cmd.args[1]
 
@@ -204,14 +220,16 @@ func handlerAddFeed(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("couldn't create feed follow: %v", err)
 	}
 
-	fmt.Printf("Feed created successfully:\n")
-	fmt.Printf("  Name: %s\n", feed.Name)
-	fmt.Printf("  URL: %s\n", feed.Url)
-	fmt.Printf("  ID: %s\n", feed.ID)
+	out := followOutput{FeedName: feed.Name, FeedURL: feed.Url, UserName: user.Name}
+	return s.out.render(out, func() error {
+		fmt.Printf("Feed created successfully:\n")
+		fmt.Printf("  Name: %s\n", feed.Name)
+		fmt.Printf("  URL: %s\n", feed.Url)
+		fmt.Printf("  ID: %s\n", feed.ID)
 
-	fmt.Printf("  Following: %s\n", follows[0].FeedName)
-
-	return nil
+		fmt.Printf("  Following: %s\n", follows[0].FeedName)
+		return nil
+	})
 }
 
 func handlerGetFeeds(s *state, cmd command) error {
@@ -220,28 +238,74 @@ func handlerGetFeeds(s *state, cmd command) error {
 		return err
 	}
 
-	fmt.Println("List of Feeds:")
-	for i := range feeds {
-		fmt.Printf("  Name: %s\n", feeds[i].Name)
-		fmt.Printf("  URL: %s\n", feeds[i].Url)
-		fmt.Printf("  Added by: %s\n", feeds[i].Username)
+	out := make([]feedOutput, 0, len(feeds))
+	for _, feed := range feeds {
+		out = append(out, feedOutput{Name: feed.Name, URL: feed.Url, AddedBy: feed.Username})
 	}
 
-	return nil
+	return s.out.render(out, func() error {
+		fmt.Println("List of Feeds:")
+		for i := range feeds {
+			fmt.Printf("  Name: %s\n", feeds[i].Name)
+			fmt.Printf("  URL: %s\n", feeds[i].Url)
+			fmt.Printf("  Added by: %s\n", feeds[i].Username)
+		}
+		return nil
+	})
 }
 
-func handlerFollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("expected 1 arguments: url")
+func handlerFeedStatus(s *state, cmd command) error {
+	statuses, err := s.db.GetFeedStatuses(context.Background())
+	if err != nil {
+		return fmt.Errorf("couldn't get feed statuses: %v", err)
+	}
+
+	out := make([]feedStatusOutput, 0, len(statuses))
+	for _, status := range statuses {
+		out = append(out, newFeedStatusOutput(status))
 	}
 
+	return s.out.render(out, func() error {
+		printFeedStatuses(statuses)
+		return nil
+	})
+}
+
+func printFeedStatuses(statuses []database.GetFeedStatusesRow) {
+	for _, status := range statuses {
+		fmt.Printf("\nName: %s\n", status.Name)
+		fmt.Printf("URL: %s\n", status.Url)
+		fmt.Printf("Last successful fetch: %s\n", formatNullTime(status.LastSuccessAt))
+		if status.LastStatusCode.Valid {
+			fmt.Printf("Last HTTP status: %d\n", status.LastStatusCode.Int32)
+		} else {
+			fmt.Printf("Last HTTP status: -\n")
+		}
+		fmt.Printf("Consecutive failures: %d\n", status.ConsecutiveFailures)
+		if status.LastError.Valid {
+			fmt.Printf("Last error: %s\n", status.LastError.String)
+		}
+		fmt.Printf("Average items per fetch: %.1f\n", status.AvgItemsPerFetch.Float64)
+		fmt.Printf("Newest post: %s\n", formatNullTime(status.NewestPostAt))
+		fmt.Println("----------------------")
+	}
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return "never"
+	}
+	return t.Time.Format(time.RFC1123)
+}
+
+func handlerFollow(s *state, cmd command, user database.User) error {
 	url := cmd.args[0]
 
 	dbFeed, err := s.db.GetFeedByURL(context.Background(), url)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// Parse and fetch the feed first
-			rssFeed, err := fetchFeed(context.Background(), url)
+			result, err := fetchFeed(context.Background(), url, feedCache{})
 			if err != nil {
 				return fmt.Errorf("couldn't fetch feed: %v", err)
 			}
@@ -251,7 +315,7 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 				ID:        uuid.New(),
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
-				Name:      rssFeed.Channel.Title,
+				Name:      result.Feed.Channel.Title,
 				Url:       url,
 				UserID:    user.ID,
 			})
@@ -271,35 +335,43 @@ func handlerFollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("couldn't create follow: %v", err)
 	}
 
-	if len(follow) > 0 {
-		fmt.Printf("Followed feed '%v' for user '%v'\n", follow[0].FeedName, follow[0].UserName)
+	if len(follow) == 0 {
+		return nil
 	}
 
-	return nil
+	out := followOutput{FeedName: follow[0].FeedName, FeedURL: dbFeed.Url, UserName: follow[0].UserName}
+	return s.out.render(out, func() error {
+		fmt.Printf("Followed feed '%v' for user '%v'\n", follow[0].FeedName, follow[0].UserName)
+		return nil
+	})
 }
 
 func handlerFollowing(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 0 {
-		return fmt.Errorf("following command takes no arguments")
-	}
-
 	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
 	if err != nil {
 		return fmt.Errorf("couldn't get follows: %v", err)
 	}
 
+	out := make([]followOutput, 0, len(follows))
 	for _, follow := range follows {
-		fmt.Printf("%v\n", follow.FeedName)
+		out = append(out, followOutput{
+			FeedName:    follow.FeedName,
+			FeedURL:     follow.FeedUrl,
+			UserName:    follow.UserName,
+			Folder:      nullString(follow.Folder.String, follow.Folder.Valid),
+			UnreadCount: follow.UnreadCount,
+		})
 	}
 
-	return nil
+	return s.out.render(out, func() error {
+		for _, follow := range follows {
+			fmt.Printf("%v (%d unread)\n", follow.FeedName, follow.UnreadCount)
+		}
+		return nil
+	})
 }
 
 func handlerUnfollow(s *state, cmd command, user database.User) error {
-	if len(cmd.args) != 1 {
-		return fmt.Errorf("unfollow command requires exactly one argument")
-	}
-
 	url := cmd.args[0]
 	feed, err := s.db.GetFeedByURL(context.Background(), url)
 	if err != nil {
@@ -317,10 +389,18 @@ func handlerUnfollow(s *state, cmd command, user database.User) error {
 		return fmt.Errorf("failed to unfollow the feed: %v", err)
 	}
 
-	return nil
+	out := followOutput{FeedName: feed.Name, FeedURL: feed.Url, UserName: user.Name}
+	return s.out.render(out, func() error {
+		fmt.Printf("Unfollowed feed '%v'\n", feed.Name)
+		return nil
+	})
 }
 
 func handlerBrowse(s *state, cmd command, user database.User) error {
+	feed := cmd.stringFlag("feed")
+	cursor := cmd.stringFlag("cursor")
+	sortOrder := cmd.stringFlag("sort")
+
 	limit := 2 // default limit
 
 	if len(cmd.args) > 0 {
@@ -331,25 +411,323 @@ func handlerBrowse(s *state, cmd command, user database.User) error {
 		limit = parsedLimit
 	}
 
-	posts, err := s.db.GetPostsForUser(context.Background(), database.GetPostsForUserParams{
-		UserID: user.ID,
-		Limit:  int32(limit),
-	})
+	params := database.BrowsePostsNewestParams{
+		UserID:      user.ID,
+		IncludeRead: cmd.boolFlag("all") || !cmd.boolFlag("unread"),
+		Feed:        sql.NullString{String: feed, Valid: feed != ""},
+		MaxResults:  int32(limit),
+	}
+
+	var err error
+	if params.Since, err = parseDateFlag("since", cmd.stringFlag("since")); err != nil {
+		return err
+	}
+	if params.Until, err = parseDateFlag("until", cmd.stringFlag("until")); err != nil {
+		return err
+	}
+
+	if cursor != "" {
+		publishedAt, postID, err := parseBrowseCursor(cursor)
+		if err != nil {
+			return err
+		}
+		params.CursorPublishedAt = sql.NullTime{Time: publishedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: postID, Valid: true}
+	}
+
+	var posts []database.BrowsePostsNewestRow
+	switch sortOrder {
+	case "newest":
+		posts, err = s.db.BrowsePostsNewest(context.Background(), params)
+	case "oldest":
+		var oldest []database.BrowsePostsOldestRow
+		oldest, err = s.db.BrowsePostsOldest(context.Background(), database.BrowsePostsOldestParams(params))
+		for _, post := range oldest {
+			posts = append(posts, database.BrowsePostsNewestRow(post))
+		}
+	default:
+		return fmt.Errorf("invalid sort order: %s (expected newest or oldest)", sortOrder)
+	}
 
 	if err != nil {
 		return fmt.Errorf("couldn't get posts: %v", err)
 	}
 
+	return s.out.render(newPostOutputs(posts), func() error {
+		if err := printPosts(s, posts); err != nil {
+			return err
+		}
+
+		// A full page means there may be more to see
+		if len(posts) > 0 && len(posts) == limit {
+			last := posts[len(posts)-1]
+			fmt.Printf("\nNext page: --cursor %s\n", formatBrowseCursor(last.PublishedAt, last.ID))
+		}
+		return nil
+	})
+}
+
+// Browse cursors point at the last post shown, as "<published_at>,<id>".
+func formatBrowseCursor(publishedAt time.Time, postID uuid.UUID) string {
+	return publishedAt.Format(time.RFC3339Nano) + "," + postID.String()
+}
+
+func parseBrowseCursor(cursor string) (time.Time, uuid.UUID, error) {
+	publishedAtPart, idPart, ok := strings.Cut(cursor, ",")
+	if !ok {
+		return time.Time{}, uuid.Nil, fmt.Errorf("invalid cursor: %s", cursor)
+	}
+
+	publishedAt, err := time.Parse(time.RFC3339Nano, publishedAtPart)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+
+	postID, err := uuid.Parse(idPart)
+	if err != nil {
+		return time.Time{}, uuid.Nil, fmt.Errorf("invalid cursor: %v", err)
+	}
+
+	return publishedAt, postID, nil
+}
+
+// parseDateFlag parses an optional date given to a --since/--until style flag.
+func parseDateFlag(name, value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+
+	date, err := parsePubDate(value)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("invalid date for --%s: %v", name, err)
+	}
+	return sql.NullTime{Time: date, Valid: true}, nil
+}
+
+func printPosts(s *state, posts []database.BrowsePostsNewestRow) error {
 	for _, post := range posts {
-		fmt.Printf("\nTitle: %s\n", post.Title)
+		fmt.Printf("\nID: %s\n", post.ID)
+		fmt.Printf("Title: %s\n", post.Title)
 		fmt.Printf("Description: %s\n", post.Description.String)
 		fmt.Printf("URL: %s\n", post.Url)
 		fmt.Printf("Published: %v\n", post.PublishedAt)
+		if err := printPostChanges(s, post); err != nil {
+			return err
+		}
 		fmt.Println("----------------------")
 	}
 	return nil
 }
 
+func handlerRead(s *state, cmd command, user database.User) error {
+	postID, err := parsePostID(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: user.ID,
+		PostID: postID,
+		ReadAt: time.Now(),
+	})
+	if err != nil {
+		// Foreign key violation means the post doesn't exist
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return fmt.Errorf("no post found with the ID: %v", postID)
+		}
+		return fmt.Errorf("couldn't mark post as read: %v", err)
+	}
+
+	return s.out.render(postActionOutput{PostID: postID, Action: "read"}, func() error {
+		fmt.Printf("Marked post %s as read\n", postID)
+		return nil
+	})
+}
+
+// parsePostID parses the single post id argument taken by read, star and
+// unstar.
+func parsePostID(cmd command) (uuid.UUID, error) {
+	postID, err := uuid.Parse(cmd.args[0])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid post id: %v", err)
+	}
+	return postID, nil
+}
+
+func handlerStar(s *state, cmd command, user database.User) error {
+	postID, err := parsePostID(cmd)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.StarPost(context.Background(), database.StarPostParams{
+		UserID:    user.ID,
+		PostID:    postID,
+		StarredAt: time.Now(),
+	})
+	if err != nil {
+		// Foreign key violation means the post doesn't exist
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return fmt.Errorf("no post found with the ID: %v", postID)
+		}
+		return fmt.Errorf("couldn't star post: %v", err)
+	}
+
+	return s.out.render(postActionOutput{PostID: postID, Action: "star"}, func() error {
+		fmt.Printf("Starred post %s\n", postID)
+		return nil
+	})
+}
+
+func handlerUnstar(s *state, cmd command, user database.User) error {
+	postID, err := parsePostID(cmd)
+	if err != nil {
+		return err
+	}
+
+	count, err := s.db.UnstarPost(context.Background(), database.UnstarPostParams{
+		UserID: user.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		return fmt.Errorf("couldn't unstar post: %v", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("post %s is not starred", postID)
+	}
+
+	return s.out.render(postActionOutput{PostID: postID, Action: "unstar"}, func() error {
+		fmt.Printf("Unstarred post %s\n", postID)
+		return nil
+	})
+}
+
+func handlerStarred(s *state, cmd command, user database.User) error {
+	posts, err := s.db.GetStarredPostsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get starred posts: %v", err)
+	}
+
+	// The starred query selects the same columns as browse
+	rows := make([]database.BrowsePostsNewestRow, 0, len(posts))
+	for _, post := range posts {
+		rows = append(rows, database.BrowsePostsNewestRow(post))
+	}
+
+	return s.out.render(newPostOutputs(rows), func() error {
+		return printPosts(s, rows)
+	})
+}
+
+func handlerSearch(s *state, cmd command, user database.User) error {
+	feed := cmd.stringFlag("feed")
+
+	params := database.SearchPostsForUserParams{
+		Query:      strings.Join(cmd.args, " "),
+		UserID:     user.ID,
+		Feed:       sql.NullString{String: feed, Valid: feed != ""},
+		MaxResults: int32(cmd.intFlag("limit")),
+	}
+
+	var err error
+	if params.Since, err = parseDateFlag("since", cmd.stringFlag("since")); err != nil {
+		return err
+	}
+
+	results, err := s.db.SearchPostsForUser(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't search posts: %v", err)
+	}
+
+	out := make([]searchResultOutput, 0, len(results))
+	for _, result := range results {
+		out = append(out, searchResultOutput{
+			ID:          result.ID,
+			Title:       result.Title,
+			FeedName:    result.FeedName,
+			URL:         result.Url,
+			PublishedAt: result.PublishedAt,
+			Rank:        result.Rank,
+			Snippet:     result.Snippet,
+		})
+	}
+
+	return s.out.render(out, func() error {
+		if len(results) == 0 {
+			fmt.Println("No matching posts")
+			return nil
+		}
+
+		for _, result := range results {
+			fmt.Printf("\nID: %s\n", result.ID)
+			fmt.Printf("Title: %s\n", result.Title)
+			fmt.Printf("Feed: %s\n", result.FeedName)
+			fmt.Printf("URL: %s\n", result.Url)
+			fmt.Printf("Published: %v\n", result.PublishedAt)
+			fmt.Printf("Rank: %.3f\n", result.Rank)
+			fmt.Printf("%s\n", result.Snippet)
+			fmt.Println("----------------------")
+		}
+		return nil
+	})
+}
+
+func handlerMarkRead(s *state, cmd command, user database.User) error {
+	feedURL := cmd.stringFlag("feed")
+
+	params := database.MarkPostsReadParams{
+		UserID:  user.ID,
+		ReadAt:  time.Now(),
+		FeedUrl: sql.NullString{String: feedURL, Valid: feedURL != ""},
+	}
+
+	var err error
+	if params.Before, err = parseDateFlag("before", cmd.stringFlag("before")); err != nil {
+		return err
+	}
+
+	count, err := s.db.MarkPostsRead(context.Background(), params)
+	if err != nil {
+		return fmt.Errorf("couldn't mark posts as read: %v", err)
+	}
+
+	return s.out.render(countOutput{Action: "markread", Count: count}, func() error {
+		fmt.Printf("Marked %d post(s) as read\n", count)
+		return nil
+	})
+}
+
+// printPostChanges flags a post the publisher has edited since we first saw
+// it and summarises what changed in the latest edit.
+func printPostChanges(s *state, post database.BrowsePostsNewestRow) error {
+	if !post.UpdatedAt.After(post.CreatedAt) {
+		return nil
+	}
+
+	revisions, err := s.db.GetPostRevisions(context.Background(), post.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get revisions: %v", err)
+	}
+	if len(revisions) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Updated: %v (%d revision(s))\n", post.UpdatedAt, len(revisions))
+
+	previous := revisions[0]
+	if previous.Title != post.Title {
+		fmt.Printf("  Title was: %s\n", previous.Title)
+	}
+	if previous.Description != post.Description {
+		fmt.Println("  Description changed")
+	}
+	if previous.Content != post.Content {
+		fmt.Println("  Content changed")
+	}
+
+	return nil
+}
+
 func middlewareLoggedIn(handler func(s *state, cmd command, user database.User) error) func(*state, command) error {
 	return func(s *state, cmd command) error {
 		currentUser, err := s.db.GetUser(context.Background(), s.configFile.Current_user_name)
@@ -360,62 +738,315 @@ func middlewareLoggedIn(handler func(s *state, cmd command, user database.User)
 	}
 }
 
+// registerCommands adds every gator command to the registry. The order here
+// is the order `gator help` lists them in.
+func registerCommands(cmds *commands) {
+	cmds.register(commandSpec{
+		name:        "help",
+		description: "Show the list of commands or the details of one",
+		args: []argSpec{{name: "command", optional: true, complete: func(*state) []completion {
+			return cmds.commandCompletions()
+		}}},
+		handler: cmds.handlerHelp,
+		noSetup: true,
+	})
+	cmds.register(commandSpec{
+		name:        "completion",
+		description: "Print a shell completion script for bash, zsh or fish",
+		args: []argSpec{{name: "shell", complete: func(*state) []completion {
+			return stringCompletions([]string{"bash", "zsh", "fish"})
+		}}},
+		handler: handlerCompletion,
+		noSetup: true,
+	})
+	cmds.register(commandSpec{
+		name:        "__complete",
+		description: "Print completion candidates for a partial command line",
+		hidden:      true,
+		args:        []argSpec{{name: "shell"}, {name: "line"}},
+		handler:     cmds.handlerComplete,
+		noSetup:     true,
+	})
+	cmds.register(commandSpec{
+		name:        "config",
+		description: "Show or change settings: get [key], set <key> <value>, unset <key>, path, validate",
+		args: []argSpec{
+			{name: "action", complete: func(*state) []completion { return stringCompletions(configActions) }},
+			{name: "key", optional: true, complete: func(*state) []completion { return stringCompletions(configGator.Keys()) }},
+			{name: "value", optional: true},
+		},
+		handler: handlerConfig,
+		noSetup: true,
+	})
+	cmds.register(commandSpec{
+		name:        "profile",
+		description: "Manage named profiles: list, use <name>, add <name> [db_url], remove <name>",
+		args: []argSpec{
+			{name: "action", complete: func(*state) []completion { return stringCompletions(profileActions) }},
+			{name: "name", optional: true, complete: completeProfiles},
+			{name: "db_url", optional: true},
+		},
+		handler: handlerProfile,
+		noSetup: true,
+	})
+	cmds.register(commandSpec{
+		name:        "migrate",
+		description: "Manage the database schema: up, down (one step), status, version",
+		args: []argSpec{{name: "action", complete: func(*state) []completion {
+			return stringCompletions(migrateActions)
+		}}},
+		handler:       handlerMigrate,
+		noSchemaCheck: true,
+	})
+	cmds.register(commandSpec{
+		name:        "login",
+		description: "Log in as an existing user",
+		args:        []argSpec{{name: "username", complete: completeUsernames}},
+		handler:     handlerLogin,
+	})
+	cmds.register(commandSpec{
+		name:        "register",
+		description: "Create a new user and log in as them",
+		args:        []argSpec{{name: "username"}},
+		handler:     handlerRegister,
+	})
+	cmds.register(commandSpec{
+		name:        "reset",
+		description: "Delete all users and their data",
+		handler:     handlerResetUsers,
+	})
+	cmds.register(commandSpec{
+		name:        "users",
+		description: "List all users",
+		handler:     handlerGetUsers,
+	})
+	cmds.register(commandSpec{
+		name:        "agg",
+		description: "Fetch feeds every interval (e.g. 1m) until stopped, optionally several at once",
+		args:        []argSpec{{name: "interval", optional: true}, {name: "concurrency", optional: true}},
+		handler:     handlerAgg,
+	})
+	cmds.register(commandSpec{
+		name:        "addfeed",
+		description: "Add a new feed and follow it",
+		args:        []argSpec{{name: "name"}, {name: "url"}},
+		handler:     middlewareLoggedIn(handlerAddFeed),
+	})
+	cmds.register(commandSpec{
+		name:        "feeds",
+		description: "List all feeds",
+		handler:     handlerGetFeeds,
+	})
+	cmds.register(commandSpec{
+		name:        "feedstatus",
+		description: "Show fetch health for every feed",
+		handler:     handlerFeedStatus,
+	})
+	cmds.register(commandSpec{
+		name:        "follow",
+		description: "Follow a feed by URL, adding it if needed",
+		args:        []argSpec{{name: "url", complete: completeFeedURLs}},
+		handler:     middlewareLoggedIn(handlerFollow),
+	})
+	cmds.register(commandSpec{
+		name:        "following",
+		description: "List the feeds you follow with their unread counts",
+		handler:     middlewareLoggedIn(handlerFollowing),
+	})
+	cmds.register(commandSpec{
+		name:        "unfollow",
+		description: "Stop following a feed",
+		args:        []argSpec{{name: "url", complete: completeFeedURLs}},
+		handler:     middlewareLoggedIn(handlerUnfollow),
+	})
+	cmds.register(commandSpec{
+		name:        "browse",
+		description: "Browse posts from the feeds you follow, unread only by default",
+		args:        []argSpec{{name: "limit", optional: true}},
+		flags: func(fs *flag.FlagSet) {
+			fs.Bool("all", false, "include posts you have already read")
+			fs.Bool("unread", true, "only show unread posts; --unread=false is the same as --all")
+			fs.String("feed", "", "only show posts from the feed with this URL or name")
+			fs.String("since", "", "only show posts published on or after this date")
+			fs.String("until", "", "only show posts published before this date")
+			fs.String("sort", "newest", "sort order: newest or oldest")
+			fs.String("cursor", "", "continue from the cursor printed by the previous page")
+		},
+		flagCompletions: map[string]func(*state) []completion{
+			"feed": completeFeeds,
+			"sort": func(*state) []completion { return stringCompletions([]string{"newest", "oldest"}) },
+		},
+		handler: middlewareLoggedIn(handlerBrowse),
+	})
+	cmds.register(commandSpec{
+		name:        "read",
+		description: "Mark a post as read",
+		args:        []argSpec{{name: "post-id"}},
+		handler:     middlewareLoggedIn(handlerRead),
+	})
+	cmds.register(commandSpec{
+		name:        "markread",
+		description: "Mark posts as read in bulk",
+		flags: func(fs *flag.FlagSet) {
+			fs.String("feed", "", "only mark posts from the feed with this URL")
+			fs.String("before", "", "only mark posts published before this date")
+		},
+		flagCompletions: map[string]func(*state) []completion{"feed": completeFeedURLs},
+		handler:         middlewareLoggedIn(handlerMarkRead),
+	})
+	cmds.register(commandSpec{
+		name:        "star",
+		description: "Star a post to keep it",
+		args:        []argSpec{{name: "post-id"}},
+		handler:     middlewareLoggedIn(handlerStar),
+	})
+	cmds.register(commandSpec{
+		name:        "unstar",
+		description: "Remove the star from a post",
+		args:        []argSpec{{name: "post-id"}},
+		handler:     middlewareLoggedIn(handlerUnstar),
+	})
+	cmds.register(commandSpec{
+		name:        "starred",
+		description: "List your starred posts",
+		handler:     middlewareLoggedIn(handlerStarred),
+	})
+	cmds.register(commandSpec{
+		name:        "search",
+		description: "Search the posts of the feeds you follow",
+		args:        []argSpec{{name: "query", variadic: true}},
+		flags: func(fs *flag.FlagSet) {
+			fs.String("feed", "", "only search the feed with this URL or name")
+			fs.String("since", "", "only search posts published on or after this date")
+			fs.Int("limit", 10, "maximum number of results")
+		},
+		flagCompletions: map[string]func(*state) []completion{"feed": completeFeeds},
+		handler:         middlewareLoggedIn(handlerSearch),
+	})
+	cmds.register(commandSpec{
+		name:        "import",
+		description: "Import and follow the feeds in an OPML file",
+		args:        []argSpec{{name: "file"}},
+		handler:     middlewareLoggedIn(handlerImport),
+	})
+	cmds.register(commandSpec{
+		name:        "export",
+		description: "Export the feeds you follow as OPML to stdout or a file",
+		args:        []argSpec{{name: "file", optional: true}},
+		handler:     middlewareLoggedIn(handlerExport),
+	})
+}
+
+// globalOptions holds the flags accepted before the command name.
+type globalOptions struct {
+	output     string
+	configPath string
+	profile    string
+}
+
+func newGlobalFlagSet(opts *globalOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("gator", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.output, "output", "text", "output format: "+strings.Join(outputFormats, ", "))
+	fs.StringVar(&opts.configPath, "config", "", "path to the config file (default $GATOR_CONFIG or $XDG_CONFIG_HOME/gator/config.json)")
+	fs.StringVar(&opts.profile, "profile", "", "profile to use for this run (default $GATOR_PROFILE or the active profile)")
+	return fs
+}
+
 func main() {
 
+	// initialize handers
+	cmds := &commands{
+		handlers: make(map[string]commandSpec),
+	}
+	registerCommands(cmds)
+
+	// Global options come before the command name
+	var global globalOptions
+	globalFlags := newGlobalFlagSet(&global)
+	err := globalFlags.Parse(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		cmds.printHelp(os.Stdout)
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
+	if globalFlags.NArg() < 1 {
+		cmds.printHelp(os.Stderr)
+		os.Exit(1)
+	}
+
+	out, err := newRenderer(global.output, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+
 	// initialize configfile
 	c := &configGator.Config{}
+	if global.configPath != "" {
+		configGator.SetPath(global.configPath)
+	}
+	if global.profile != "" {
+		configGator.SetProfile(global.profile)
+	}
 
 	// Read the configuration
-	err := configGator.ReadConfig(c)
+	err = configGator.ReadConfig(c)
 	if err != nil {
-		fmt.Println("Failed to read config:", err)
+		fmt.Fprintln(os.Stderr, "Failed to read config:", err)
 		os.Exit(1)
 	}
 
+	cmd := command{
+		name: globalFlags.Arg(0),
+		args: globalFlags.Args()[1:],
+	}
+
+	// Ask for a database on first run rather than failing on the first query
+	if spec, exists := cmds.handlers[cmd.name]; exists && !spec.noSetup && c.Db_url == "" {
+		if err := firstRunSetup(c); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	}
+
 	db, err := sql.Open("postgres", c.Db_url)
 	if err != nil {
-		fmt.Println("Failed to connect to database:", err)
+		fmt.Fprintln(os.Stderr, "Failed to connect to database:", err)
 		os.Exit(1)
 	}
 
+	// Refuse to run queries the database's schema can't answer yet
+	if spec, exists := cmds.handlers[cmd.name]; exists && !spec.noSetup && !spec.noSchemaCheck {
+		if err := checkSchema(context.Background(), db); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			os.Exit(1)
+		}
+	}
+
 	dbQueries := database.New(db)
 
 	// initialize state to hold configfile and dbqueries
 	s := &state{
 		configFile: c,
 		db:         dbQueries,
-	}
-
-	// initialize handers
-	cmds := &commands{
-		handlers: make(map[string]func(*state, command) error),
-	}
-
-	cmds.register("login", handlerLogin)
-	cmds.register("register", handlerRegister)
-	cmds.register("reset", handlerResetUsers)
-	cmds.register("users", handlerGetUsers)
-	cmds.register("agg", handlerAgg)
-	cmds.register("addfeed", middlewareLoggedIn(handlerAddFeed))
-	cmds.register("feeds", handlerGetFeeds)
-	cmds.register("follow", middlewareLoggedIn(handlerFollow))
-	cmds.register("following", middlewareLoggedIn(handlerFollowing))
-	cmds.register("unfollow", middlewareLoggedIn(handlerUnfollow))
-	cmds.register("browse", middlewareLoggedIn(handlerBrowse))
-
-	if len(os.Args) < 2 {
-		fmt.Println("not enough arguments")
-		os.Exit(1)
-	}
-
-	cmd := command{
-		name: os.Args[1],
-		args: os.Args[2:],
+		conn:       db,
+		out:        out,
 	}
 
 	err = cmds.run(s, cmd)
 	if err != nil {
-		fmt.Println("Error:", err)
+		fmt.Fprintln(os.Stderr, "Error:", err)
+
+		var usageErr *usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "Usage: %s\n", usageErr.spec.usage())
+			fmt.Fprintf(os.Stderr, "Run 'gator help %s' for details.\n", usageErr.spec.name)
+		}
 		os.Exit(1)
 	}
 
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// The goose migrations in sql/schema, compiled in so gator can set up and
+// upgrade its own database. They are applied the way goose applies them and
+// recorded in goose's version table, so a database migrated with the goose
+// CLI and one migrated by gator look the same.
+//
+//go:embed sql/schema/*.sql
+var schemaFiles embed.FS
+
+type migration struct {
+	version       int64
+	name          string
+	up            string
+	down          string
+	noTransaction bool
+}
+
+var migrateActions = []string{"up", "down", "status", "version"}
+
+// migrations holds the embedded migrations in version order.
+var migrations = mustLoadMigrations()
+
+// expectedSchemaVersion is the newest embedded migration, which the queries
+// in internal/database are generated against.
+var expectedSchemaVersion = migrations[len(migrations)-1].version
+
+func mustLoadMigrations() []migration {
+	loaded, err := loadMigrations()
+	if err != nil {
+		panic(fmt.Sprintf("couldn't load embedded migrations: %v", err))
+	}
+	return loaded
+}
+
+func loadMigrations() ([]migration, error) {
+	files, err := schemaFiles.ReadDir("sql/schema")
+	if err != nil {
+		return nil, err
+	}
+
+	var loaded []migration
+	for _, file := range files {
+		prefix, _, ok := strings.Cut(file.Name(), "_")
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a name like 001_description.sql", file.Name())
+		}
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid version: %v", file.Name(), err)
+		}
+
+		body, err := schemaFiles.ReadFile(path.Join("sql/schema", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m := parseMigration(string(body))
+		m.version = version
+		m.name = file.Name()
+		loaded = append(loaded, m)
+	}
+	if len(loaded) == 0 {
+		return nil, errors.New("no migrations found")
+	}
+
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].version < loaded[j].version })
+	return loaded, nil
+}
+
+// parseMigration splits a goose SQL file into its Up and Down sections. The
+// sections are sent to Postgres whole, so statement annotations are dropped.
+func parseMigration(body string) migration {
+	var m migration
+	var up, down strings.Builder
+	var section *strings.Builder
+
+	for _, line := range strings.SplitAfter(body, "\n") {
+		annotation, isAnnotation := strings.CutPrefix(strings.TrimSpace(line), "-- +goose ")
+		if !isAnnotation {
+			if section != nil {
+				section.WriteString(line)
+			}
+			continue
+		}
+
+		switch strings.TrimSpace(annotation) {
+		case "Up":
+			section = &up
+		case "Down":
+			section = &down
+		case "NO TRANSACTION":
+			m.noTransaction = true
+		}
+	}
+
+	m.up = up.String()
+	m.down = down.String()
+	return m
+}
+
+func handlerMigrate(s *state, cmd command) error {
+	ctx := context.Background()
+
+	switch cmd.args[0] {
+	case "up":
+		return migrateUp(ctx, s)
+	case "down":
+		return migrateDown(ctx, s)
+	case "status":
+		return migrateStatus(ctx, s)
+	case "version":
+		version, err := schemaVersion(ctx, s.conn)
+		if err != nil {
+			return fmt.Errorf("couldn't read the schema version: %v", err)
+		}
+		out := configValidationOutput{SchemaVersion: version, ExpectedSchemaVersion: expectedSchemaVersion}
+		return s.out.render(out, func() error {
+			fmt.Printf("Schema version: %d (this gator expects %d)\n", version, expectedSchemaVersion)
+			return nil
+		})
+	default:
+		return &usageError{spec: cmd.spec, message: fmt.Sprintf("unknown migrate action: %s (expected one of %s)", cmd.args[0], strings.Join(migrateActions, ", "))}
+	}
+}
+
+// migrateUp applies every migration newer than the current version.
+func migrateUp(ctx context.Context, s *state) error {
+	if err := ensureVersionTable(ctx, s.conn); err != nil {
+		return err
+	}
+
+	current, err := schemaVersion(ctx, s.conn)
+	if err != nil {
+		return fmt.Errorf("couldn't read the schema version: %v", err)
+	}
+
+	out := []migrationOutput{}
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		if err := applyMigration(ctx, s.conn, m.up, m.noTransaction,
+			"INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, true)", m.version); err != nil {
+			return fmt.Errorf("couldn't apply %s: %v", m.name, err)
+		}
+		out = append(out, migrationOutput{Version: m.version, Name: m.name, Applied: true})
+	}
+
+	return s.out.render(out, func() error {
+		for _, m := range out {
+			fmt.Printf("Applied %s\n", m.Name)
+		}
+		fmt.Printf("Schema is at version %d\n", expectedSchemaVersion)
+		return nil
+	})
+}
+
+// migrateDown rolls back the newest applied migration.
+func migrateDown(ctx context.Context, s *state) error {
+	current, err := schemaVersion(ctx, s.conn)
+	if err != nil {
+		return fmt.Errorf("couldn't read the schema version: %v", err)
+	}
+	if current == 0 {
+		return errors.New("no migrations to roll back")
+	}
+
+	for _, m := range migrations {
+		if m.version != current {
+			continue
+		}
+
+		if err := applyMigration(ctx, s.conn, m.down, m.noTransaction,
+			"DELETE FROM goose_db_version WHERE version_id = $1", m.version); err != nil {
+			return fmt.Errorf("couldn't roll back %s: %v", m.name, err)
+		}
+
+		out := migrationOutput{Version: m.version, Name: m.name, Applied: false}
+		return s.out.render(out, func() error {
+			fmt.Printf("Rolled back %s\n", m.name)
+			return nil
+		})
+	}
+
+	return fmt.Errorf("the database is at version %d, which this gator doesn't know about", current)
+}
+
+// applyMigration runs one migration section and records it in the version
+// table, in a single transaction unless the migration opts out.
+func applyMigration(ctx context.Context, db *sql.DB, statements string, noTransaction bool, record string, version int64) error {
+	if noTransaction {
+		if _, err := db.ExecContext(ctx, statements); err != nil {
+			return err
+		}
+		_, err := db.ExecContext(ctx, record, version)
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, statements); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ensureVersionTable creates goose's version table the way goose does,
+// starting at version 0.
+func ensureVersionTable(ctx context.Context, db *sql.DB) error {
+	_, err := db.ExecContext(ctx, "SELECT 1 FROM goose_db_version LIMIT 1")
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != "42P01" {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+CREATE TABLE goose_db_version (
+	id SERIAL PRIMARY KEY,
+	version_id BIGINT NOT NULL,
+	is_applied BOOLEAN NOT NULL,
+	tstamp TIMESTAMP NULL DEFAULT NOW()
+);
+INSERT INTO goose_db_version (version_id, is_applied) VALUES (0, true);`)
+	if err != nil {
+		return fmt.Errorf("couldn't create the migration version table: %v", err)
+	}
+	return nil
+}
+
+func migrateStatus(ctx context.Context, s *state) error {
+	appliedAt, err := migrationTimes(ctx, s.conn)
+	if err != nil {
+		return fmt.Errorf("couldn't read applied migrations: %v", err)
+	}
+
+	out := make([]migrationOutput, 0, len(migrations))
+	for _, m := range migrations {
+		at, applied := appliedAt[m.version]
+		out = append(out, migrationOutput{Version: m.version, Name: m.name, Applied: applied, AppliedAt: nullTime(at, applied)})
+	}
+
+	return s.out.render(out, func() error {
+		for _, m := range out {
+			status := "Pending"
+			if m.AppliedAt != nil {
+				status = m.AppliedAt.Format(time.RFC1123)
+			}
+			fmt.Printf("  %-31s %s\n", status, m.Name)
+		}
+		return nil
+	})
+}
+
+// migrationTimes returns when each currently applied migration was applied.
+func migrationTimes(ctx context.Context, db *sql.DB) (map[int64]time.Time, error) {
+	current, err := schemaVersion(ctx, db)
+	if err != nil || current == 0 {
+		return map[int64]time.Time{}, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version_id, is_applied, tstamp FROM goose_db_version ORDER BY id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := map[int64]time.Time{}
+	seen := map[int64]bool{}
+	for rows.Next() {
+		var version int64
+		var applied bool
+		var tstamp sql.NullTime
+		if err := rows.Scan(&version, &applied, &tstamp); err != nil {
+			return nil, err
+		}
+		// Only the newest row for a version counts
+		if seen[version] {
+			continue
+		}
+		seen[version] = true
+		if applied && version > 0 {
+			appliedAt[version] = tstamp.Time
+		}
+	}
+	return appliedAt, rows.Err()
+}
+
+// checkSchema refuses to run against a database that is missing migrations,
+// rather than letting a query fail halfway through a command.
+func checkSchema(ctx context.Context, db *sql.DB) error {
+	version, err := schemaVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("couldn't read the schema version: %v", err)
+	}
+	if version < expectedSchemaVersion {
+		return fmt.Errorf("the database schema is at version %d but this gator needs version %d: run 'gator migrate up'",
+			version, expectedSchemaVersion)
+	}
+	return nil
+}
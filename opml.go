@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github/jonathanpetrone/bootdevBlogAgg/internal/database"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    OPMLHead `xml:"head"`
+	Body    OPMLBody `xml:"body"`
+}
+
+type OPMLHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type OPMLBody struct {
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+type OPMLOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Outlines []OPMLOutline `xml:"outline"`
+}
+
+// opmlFeed is a subscription found in an OPML file together with the folder
+// path of the outlines it was nested in, e.g. "Tech/Go".
+type opmlFeed struct {
+	Name    string
+	URL     string
+	HTMLURL string
+	Folder  string
+}
+
+// flattenOutlines walks the outline tree and returns every outline that has
+// an xmlUrl. Outlines without one are treated as folders.
+func flattenOutlines(outlines []OPMLOutline, folder []string) []opmlFeed {
+	var feeds []opmlFeed
+	for _, outline := range outlines {
+		name := outline.Title
+		if name == "" {
+			name = outline.Text
+		}
+
+		if outline.XMLURL == "" {
+			feeds = append(feeds, flattenOutlines(outline.Outlines, append(folder, name))...)
+			continue
+		}
+
+		if name == "" {
+			name = outline.XMLURL
+		}
+		feeds = append(feeds, opmlFeed{
+			Name:    name,
+			URL:     outline.XMLURL,
+			HTMLURL: outline.HTMLURL,
+			Folder:  strings.Join(folder, "/"),
+		})
+
+		// Some readers nest feeds under feeds; keep walking
+		feeds = append(feeds, flattenOutlines(outline.Outlines, folder)...)
+	}
+	return feeds
+}
+
+func handlerImport(s *state, cmd command, user database.User) error {
+	file, err := os.Open(cmd.args[0])
+	if err != nil {
+		return fmt.Errorf("couldn't open OPML file: %v", err)
+	}
+	defer file.Close()
+
+	doc := OPML{}
+	if err := xml.NewDecoder(file).Decode(&doc); err != nil {
+		return fmt.Errorf("couldn't parse OPML file: %v", err)
+	}
+
+	results := []importResultOutput{}
+	created, existed, failed := 0, 0, 0
+
+	for _, feed := range flattenOutlines(doc.Body.Outlines, nil) {
+		result := importResultOutput{Name: feed.Name, URL: feed.URL, Folder: feed.Folder}
+
+		status, err := importFeed(s, user, feed)
+		if err != nil {
+			message := err.Error()
+			result.Status = "failed"
+			result.Error = &message
+			failed++
+		} else {
+			result.Status = status
+			if status == "created" {
+				created++
+			} else {
+				existed++
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return s.out.render(results, func() error {
+		for _, result := range results {
+			if result.Error != nil {
+				fmt.Printf("  failed   %s (%s): %v\n", result.Name, result.URL, *result.Error)
+				continue
+			}
+			fmt.Printf("  %-8s %s (%s)\n", result.Status, result.Name, result.URL)
+		}
+
+		fmt.Printf("Imported %d feed(s): %d created, %d already existed, %d failed\n", created+existed+failed, created, existed, failed)
+		return nil
+	})
+}
+
+// importFeed creates the feed if needed and follows it for the user,
+// returning "created" or "existed".
+func importFeed(s *state, user database.User, feed opmlFeed) (string, error) {
+	ctx := context.Background()
+	status := "existed"
+
+	dbFeed, err := s.db.GetFeedByURL(ctx, feed.URL)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("error getting feed: %v", err)
+		}
+
+		// Feed names are unique but OPML titles aren't, so a title that is
+		// already taken by another feed gets the host, then the URL, added
+		for _, name := range importFeedNames(feed) {
+			dbFeed, err = s.db.CreateFeed(ctx, database.CreateFeedParams{
+				ID:        uuid.New(),
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+				Name:      name,
+				Url:       feed.URL,
+				UserID:    user.ID,
+			})
+			if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != "23505" || pqErr.Constraint != "feeds_name_key" {
+				break
+			}
+		}
+		if err != nil {
+			return "", fmt.Errorf("couldn't create feed: %v", err)
+		}
+		status = "created"
+	}
+
+	if feed.HTMLURL != "" && !dbFeed.SiteUrl.Valid {
+		err = s.db.SetFeedSiteURL(ctx, database.SetFeedSiteURLParams{
+			SiteUrl: sql.NullString{String: feed.HTMLURL, Valid: true},
+			ID:      dbFeed.ID,
+		})
+		if err != nil {
+			return "", fmt.Errorf("couldn't set site URL: %v", err)
+		}
+	}
+
+	_, err = s.db.CreateFeedFollow(ctx, database.CreateFeedFollowParams{
+		UserID: user.ID,
+		FeedID: dbFeed.ID,
+	})
+	if err != nil {
+		// Already following is fine, anything else is not
+		if pqErr, ok := err.(*pq.Error); !ok || pqErr.Code != "23505" {
+			return "", fmt.Errorf("couldn't create follow: %v", err)
+		}
+	}
+
+	if feed.Folder != "" {
+		err = s.db.SetFeedFollowFolder(ctx, database.SetFeedFollowFolderParams{
+			Folder: sql.NullString{String: feed.Folder, Valid: true},
+			UserID: user.ID,
+			FeedID: dbFeed.ID,
+		})
+		if err != nil {
+			return "", fmt.Errorf("couldn't set folder: %v", err)
+		}
+	}
+
+	return status, nil
+}
+
+// importFeedNames returns the names to try for an imported feed, in order.
+func importFeedNames(feed opmlFeed) []string {
+	names := []string{feed.Name}
+	if u, err := url.Parse(feed.URL); err == nil && u.Host != "" {
+		names = append(names, fmt.Sprintf("%s (%s)", feed.Name, u.Host))
+	}
+	return append(names, fmt.Sprintf("%s (%s)", feed.Name, feed.URL))
+}
+
+// buildOutlines turns the user's follows back into an outline tree, nesting
+// each feed under its folder path.
+func buildOutlines(follows []database.GetFeedFollowsForUserRow) []OPMLOutline {
+	root := &OPMLOutline{}
+
+	for _, follow := range follows {
+		parent := root
+		if follow.Folder.Valid && follow.Folder.String != "" {
+			for _, name := range strings.Split(follow.Folder.String, "/") {
+				parent = findOrAddFolder(parent, name)
+			}
+		}
+
+		parent.Outlines = append(parent.Outlines, OPMLOutline{
+			Text:    follow.FeedName,
+			Title:   follow.FeedName,
+			Type:    "rss",
+			XMLURL:  follow.FeedUrl,
+			HTMLURL: follow.FeedSiteUrl.String,
+		})
+	}
+
+	return root.Outlines
+}
+
+func findOrAddFolder(parent *OPMLOutline, name string) *OPMLOutline {
+	for i := range parent.Outlines {
+		if parent.Outlines[i].XMLURL == "" && parent.Outlines[i].Text == name {
+			return &parent.Outlines[i]
+		}
+	}
+	parent.Outlines = append(parent.Outlines, OPMLOutline{Text: name, Title: name})
+	return &parent.Outlines[len(parent.Outlines)-1]
+}
+
+func handlerExport(s *state, cmd command, user database.User) error {
+	follows, err := s.db.GetFeedFollowsForUser(context.Background(), user.ID)
+	if err != nil {
+		return fmt.Errorf("couldn't get follows: %v", err)
+	}
+
+	doc := OPML{
+		Version: "2.0",
+		Head: OPMLHead{
+			Title:       fmt.Sprintf("gator subscriptions for %s", user.Name),
+			DateCreated: time.Now().Format(time.RFC1123Z),
+		},
+		Body: OPMLBody{Outlines: buildOutlines(follows)},
+	}
+
+	out := os.Stdout
+	if len(cmd.args) == 1 {
+		out, err = os.Create(cmd.args[0])
+		if err != nil {
+			return fmt.Errorf("couldn't create output file: %v", err)
+		}
+		defer out.Close()
+	}
+
+	if _, err := out.WriteString(xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(out)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("couldn't write OPML: %v", err)
+	}
+
+	_, err = out.WriteString("\n")
+	return err
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github/jonathanpetrone/bootdevBlogAgg/internal/database"
+)
+
+var outputFormats = []string{"text", "json", "csv", "tsv"}
+
+// renderer writes command results in the format chosen with --output. Text
+// output is left to each handler; the other formats are derived from the
+// json tags of the record types below, so field names stay stable.
+type renderer struct {
+	format string
+	out    io.Writer
+}
+
+func newRenderer(format string, out io.Writer) (*renderer, error) {
+	for _, f := range outputFormats {
+		if f == format {
+			return &renderer{format: format, out: out}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid output format: %s (expected one of %s)", format, strings.Join(outputFormats, ", "))
+}
+
+// render writes v, a record or a slice of records, in the chosen format.
+// For text output it calls text instead.
+func (r *renderer) render(v any, text func() error) error {
+	switch r.format {
+	case "json":
+		encoder := json.NewEncoder(r.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case "csv", "tsv":
+		return r.renderTable(v)
+	default:
+		return text()
+	}
+}
+
+func (r *renderer) renderTable(v any) error {
+	rows := reflect.ValueOf(v)
+	if rows.Kind() != reflect.Slice {
+		single := reflect.MakeSlice(reflect.SliceOf(rows.Type()), 1, 1)
+		single.Index(0).Set(rows)
+		rows = single
+	}
+
+	recordType := rows.Type().Elem()
+	var header []string
+	var fields []int
+	for i := 0; i < recordType.NumField(); i++ {
+		name, _, _ := strings.Cut(recordType.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		header = append(header, name)
+		fields = append(fields, i)
+	}
+
+	table := [][]string{header}
+	for i := 0; i < rows.Len(); i++ {
+		record := make([]string, len(fields))
+		for j, field := range fields {
+			record[j] = formatField(rows.Index(i).Field(field))
+		}
+		table = append(table, record)
+	}
+
+	if r.format == "csv" {
+		writer := csv.NewWriter(r.out)
+		return writer.WriteAll(table)
+	}
+
+	// TSV has no quoting, so tabs and newlines inside values become spaces
+	replacer := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+	for _, record := range table {
+		for j := range record {
+			record[j] = replacer.Replace(record[j])
+		}
+		if _, err := fmt.Fprintln(r.out, strings.Join(record, "\t")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func formatField(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func nullString(s string, valid bool) *string {
+	if !valid {
+		return nil
+	}
+	return &s
+}
+
+func nullTime(t time.Time, valid bool) *time.Time {
+	if !valid {
+		return nil
+	}
+	return &t
+}
+
+type userOutput struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	Current   bool      `json:"current"`
+}
+
+func newUserOutput(user database.User, current string) userOutput {
+	return userOutput{
+		ID:        user.ID,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt,
+		Current:   user.Name == current,
+	}
+}
+
+type feedOutput struct {
+	Name    string `json:"name"`
+	URL     string `json:"url"`
+	AddedBy string `json:"added_by"`
+}
+
+type feedStatusOutput struct {
+	Name                string     `json:"name"`
+	URL                 string     `json:"url"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastStatusCode      *int32     `json:"last_status_code"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	LastError           *string    `json:"last_error"`
+	AvgItemsPerFetch    float64    `json:"avg_items_per_fetch"`
+	NewestPostAt        *time.Time `json:"newest_post_at"`
+}
+
+func newFeedStatusOutput(status database.GetFeedStatusesRow) feedStatusOutput {
+	out := feedStatusOutput{
+		Name:                status.Name,
+		URL:                 status.Url,
+		LastSuccessAt:       nullTime(status.LastSuccessAt.Time, status.LastSuccessAt.Valid),
+		ConsecutiveFailures: status.ConsecutiveFailures,
+		LastError:           nullString(status.LastError.String, status.LastError.Valid),
+		AvgItemsPerFetch:    status.AvgItemsPerFetch.Float64,
+		NewestPostAt:        nullTime(status.NewestPostAt.Time, status.NewestPostAt.Valid),
+	}
+	if status.LastStatusCode.Valid {
+		out.LastStatusCode = &status.LastStatusCode.Int32
+	}
+	return out
+}
+
+type followOutput struct {
+	FeedName    string  `json:"feed_name"`
+	FeedURL     string  `json:"feed_url"`
+	UserName    string  `json:"user_name"`
+	Folder      *string `json:"folder"`
+	UnreadCount int64   `json:"unread_count"`
+}
+
+type postOutput struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	Description *string   `json:"description"`
+	URL         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+	Updated     bool      `json:"updated"`
+	Cursor      string    `json:"cursor"`
+}
+
+func newPostOutputs(posts []database.BrowsePostsNewestRow) []postOutput {
+	out := make([]postOutput, 0, len(posts))
+	for _, post := range posts {
+		out = append(out, postOutput{
+			ID:          post.ID,
+			Title:       post.Title,
+			Description: nullString(post.Description.String, post.Description.Valid),
+			URL:         post.Url,
+			PublishedAt: post.PublishedAt,
+			Updated:     post.UpdatedAt.After(post.CreatedAt),
+			Cursor:      formatBrowseCursor(post.PublishedAt, post.ID),
+		})
+	}
+	return out
+}
+
+type searchResultOutput struct {
+	ID          uuid.UUID `json:"id"`
+	Title       string    `json:"title"`
+	FeedName    string    `json:"feed_name"`
+	URL         string    `json:"url"`
+	PublishedAt time.Time `json:"published_at"`
+	Rank        float32   `json:"rank"`
+	Snippet     string    `json:"snippet"`
+}
+
+type postActionOutput struct {
+	PostID uuid.UUID `json:"post_id"`
+	Action string    `json:"action"`
+}
+
+type countOutput struct {
+	Action string `json:"action"`
+	Count  int64  `json:"count"`
+}
+
+type importResultOutput struct {
+	Name   string  `json:"name"`
+	URL    string  `json:"url"`
+	Folder string  `json:"folder"`
+	Status string  `json:"status"`
+	Error  *string `json:"error"`
+}
+
+type configValueOutput struct {
+	Key          string `json:"key"`
+	Value        string `json:"value"`
+	OverriddenBy string `json:"overridden_by"`
+}
+
+type configPathOutput struct {
+	Path string `json:"path"`
+}
+
+type configValidationOutput struct {
+	SchemaVersion         int64 `json:"schema_version"`
+	ExpectedSchemaVersion int64 `json:"expected_schema_version"`
+}
+
+type profileOutput struct {
+	Name            string `json:"name"`
+	Active          bool   `json:"active"`
+	InUse           bool   `json:"in_use"`
+	CurrentUserName string `json:"current_user_name"`
+}
+
+type profileActionOutput struct {
+	Profile string `json:"profile"`
+	Action  string `json:"action"`
+}
+
+type migrationOutput struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
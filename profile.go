@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	configGator "github/jonathanpetrone/bootdevBlogAgg/internal/config"
+)
+
+var profileActions = []string{"list", "use", "add", "remove"}
+
+func handlerProfile(s *state, cmd command) error {
+	action, args := cmd.args[0], cmd.args[1:]
+
+	switch action {
+	case "list":
+		if len(args) != 0 {
+			return &usageError{spec: cmd.spec, message: "profile list takes no arguments"}
+		}
+		return profileList(s)
+	case "use":
+		if len(args) != 1 {
+			return &usageError{spec: cmd.spec, message: "profile use expects a profile name"}
+		}
+		if err := s.configFile.UseProfile(args[0]); err != nil {
+			return err
+		}
+		return saveProfileChange(s, args[0], "use", "Switched to profile %s\n")
+	case "add":
+		if len(args) < 1 || len(args) > 2 {
+			return &usageError{spec: cmd.spec, message: "profile add expects a profile name and optionally a database URL"}
+		}
+		profile := configGator.Profile{}
+		if len(args) == 2 {
+			profile.Db_url = args[1]
+		}
+		if err := s.configFile.AddProfile(args[0], profile); err != nil {
+			return err
+		}
+		return saveProfileChange(s, args[0], "add", "Added profile %s\n")
+	case "remove":
+		if len(args) != 1 {
+			return &usageError{spec: cmd.spec, message: "profile remove expects a profile name"}
+		}
+		if err := s.configFile.RemoveProfile(args[0]); err != nil {
+			return err
+		}
+		return saveProfileChange(s, args[0], "remove", "Removed profile %s\n")
+	default:
+		return &usageError{spec: cmd.spec, message: fmt.Sprintf("unknown profile action: %s (expected one of %s)", action, strings.Join(profileActions, ", "))}
+	}
+}
+
+func saveProfileChange(s *state, name, action, message string) error {
+	if err := configGator.WriteConfig(s.configFile); err != nil {
+		return fmt.Errorf("couldn't save config: %v", err)
+	}
+
+	return s.out.render(profileActionOutput{Profile: name, Action: action}, func() error {
+		fmt.Printf(message, name)
+		return nil
+	})
+}
+
+func profileList(s *state) error {
+	names := s.configFile.ProfileNames()
+
+	out := make([]profileOutput, 0, len(names))
+	for _, name := range names {
+		profile, _ := s.configFile.GetProfile(name)
+		out = append(out, profileOutput{
+			Name:            name,
+			Active:          name == s.configFile.Active_profile,
+			InUse:           name == s.configFile.ProfileName(),
+			CurrentUserName: profile.Current_user_name,
+		})
+	}
+
+	return s.out.render(out, func() error {
+		for _, profile := range out {
+			marker := " "
+			if profile.Active {
+				marker = "*"
+			}
+			fmt.Printf("%s %s", marker, profile.Name)
+			if profile.InUse && !profile.Active {
+				fmt.Print(" (in use)")
+			}
+			fmt.Println()
+		}
+		return nil
+	})
+}
+
+func completeProfiles(s *state) []completion {
+	return stringCompletions(s.configFile.ProfileNames())
+}
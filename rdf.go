@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/xml"
+)
+
+// RDFFeed is an RSS 1.0 (RDF Site Summary) document. Unlike RSS 2.0 the
+// items are siblings of the channel rather than children of it.
+type RDFFeed struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+	} `xml:"channel"`
+	Items []RDFItem `xml:"item"`
+}
+
+type RDFItem struct {
+	About       string `xml:"http://www.w3.org/1999/02/22-rdf-syntax-ns# about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+func parseRDFFeed(body []byte) (*RSSFeed, error) {
+	rdf := &RDFFeed{}
+	if err := xml.Unmarshal(body, rdf); err != nil {
+		return nil, err
+	}
+
+	feed := &RSSFeed{}
+	feed.Channel.Title = rdf.Channel.Title
+	feed.Channel.Link = rdf.Channel.Link
+	feed.Channel.Description = rdf.Channel.Description
+
+	for _, item := range rdf.Items {
+		feed.Channel.Item = append(feed.Channel.Item, RSSItem{
+			Title:       item.Title,
+			Link:        item.Link,
+			Description: item.Description,
+			PubDate:     item.Date,
+			GUID:        item.About,
+		})
+	}
+
+	return feed, nil
+}
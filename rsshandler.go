@@ -1,15 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/xml"
+	"errors"
+	"fmt"
 	"github/jonathanpetrone/bootdevBlogAgg/internal/database"
 	"html"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,32 +35,120 @@ type RSSItem struct {
 	Link        string `xml:"link"`
 	Description string `xml:"description"`
 	PubDate     string `xml:"pubDate"`
+	GUID        string `xml:"guid"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
 }
 
-func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
-	feed := &RSSFeed{}
-	client := &http.Client{}
+// feedRootElement returns the local name of the document's root element,
+// e.g. "rss", "feed" or "RDF".
+func feedRootElement(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// parseFeed detects the feed format from the body, falling back to the
+// content type, and parses it into the common RSSFeed model.
+func parseFeed(contentType string, body []byte) (*RSSFeed, error) {
+	// Neither decoder accepts a UTF-8 byte order mark
+	body = bytes.TrimPrefix(body, []byte("\xef\xbb\xbf"))
+
+	if isJSONFeed(contentType, body) {
+		return parseJSONFeed(body)
+	}
+
+	root, err := feedRootElement(body)
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "feed":
+		return parseAtomFeed(body)
+	case "RDF":
+		return parseRDFFeed(body)
+	default:
+		feed := &RSSFeed{}
+		if err := xml.Unmarshal(body, feed); err != nil {
+			return nil, err
+		}
+		return feed, nil
+	}
+}
+
+// feedCache holds the validators a publisher sent with the last response, so
+// the next request can be made conditional.
+type feedCache struct {
+	ETag         string
+	LastModified string
+}
+
+type fetchResult struct {
+	Feed        *RSSFeed
+	StatusCode  int
+	NotModified bool
+	Cache       feedCache
+}
+
+// feedClient is shared by every fetch. The timeout covers the whole request
+// including reading the body, so a server that stalls can't hold up agg.
+var feedClient = &http.Client{Timeout: 30 * time.Second}
+
+func fetchFeed(ctx context.Context, feedURL string, cache feedCache) (*fetchResult, error) {
+	result := &fetchResult{Feed: &RSSFeed{}, Cache: cache}
 
 	req, err := http.NewRequestWithContext(ctx, "GET", feedURL, nil)
 	if err != nil {
-		return feed, err
+		return result, err
 	}
 
 	req.Header.Set("User-Agent", "gator")
+	if cache.ETag != "" {
+		req.Header.Set("If-None-Match", cache.ETag)
+	}
+	if cache.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cache.LastModified)
+	}
 
-	resp, err := client.Do(req)
+	resp, err := feedClient.Do(req)
 	if err != nil {
-		return feed, err
+		return result, err
 	}
 	defer resp.Body.Close()
 
+	result.StatusCode = resp.StatusCode
+
+	// A 304 may carry fresh validators; keep the old ones when it doesn't
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		result.Cache.ETag = etag
+	}
+	if lastModified := resp.Header.Get("Last-Modified"); lastModified != "" {
+		result.Cache.LastModified = lastModified
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		return result, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return feed, err
+		return result, err
 	}
 
-	if err := xml.Unmarshal(body, feed); err != nil {
-		return feed, err
+	feed, err := parseFeed(resp.Header.Get("Content-Type"), body)
+	if err != nil {
+		return result, err
 	}
 
 	feed.Channel.Title = html.UnescapeString(feed.Channel.Title)
@@ -63,52 +157,277 @@ func fetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	for i := range feed.Channel.Item {
 		feed.Channel.Item[i].Title = html.UnescapeString(feed.Channel.Item[i].Title)
 		feed.Channel.Item[i].Description = html.UnescapeString(feed.Channel.Item[i].Description)
+		feed.Channel.Item[i].Content = html.UnescapeString(feed.Channel.Item[i].Content)
 	}
 
-	return feed, nil
+	result.Feed = feed
+	return result, nil
+}
+
+// scrapeStats summarises the work done by one or more scrapeFeeds calls.
+type scrapeStats struct {
+	FeedsFetched int
+	FeedsFailed  int
+	PostsCreated int
+	PostsUpdated int
+}
+
+func (st *scrapeStats) add(other scrapeStats) {
+	st.FeedsFetched += other.FeedsFetched
+	st.FeedsFailed += other.FeedsFailed
+	st.PostsCreated += other.PostsCreated
+	st.PostsUpdated += other.PostsUpdated
+}
+
+// feedClaimLease is how long a claimed feed is reserved for the agg process
+// that claimed it. Fetching and storing a feed is done well within it; the
+// lease only runs out if that process dies without finishing.
+const feedClaimLease = 10 * time.Minute
+
+// claimNextFeeds reserves up to limit due feeds, so other agg processes skip
+// them until they have been fetched or released. The timestamp columns hold
+// gator's local time, so "now" comes from here rather than from NOW() in the
+// database, whose session time zone may differ.
+func claimNextFeeds(ctx context.Context, s *state, limit int) ([]database.Feed, error) {
+	now := time.Now()
+	return s.db.ClaimNextFeedsToFetch(ctx, database.ClaimNextFeedsToFetchParams{
+		ClaimedUntil: sql.NullTime{Time: now.Add(feedClaimLease), Valid: true},
+		Now:          sql.NullTime{Time: now, Valid: true},
+		MaxResults:   int32(limit),
+	})
 }
 
-func scrapeFeeds(ctx context.Context, s *state) error {
-	// Access the database through `s.db` and get the next feed to fetch
-	nextFeed, err := s.db.GetNextFeedToFetch(ctx)
+// releaseFeedClaim gives up the claim on a feed that wasn't fetched, so it
+// can be picked up again straight away.
+func releaseFeedClaim(ctx context.Context, s *state, feed database.Feed) {
+	if err := s.db.ReleaseFeedClaim(ctx, feed.ID); err != nil {
+		log.Printf("Error releasing feed %s: %v", feed.Url, err)
+	}
+}
+
+// scrapeFeeds claims up to concurrency feeds and fetches them in parallel,
+// one goroutine per feed.
+func scrapeFeeds(ctx context.Context, s *state, concurrency int) (scrapeStats, error) {
+	var stats scrapeStats
+
+	// Claim a batch of feeds so other agg processes skip them
+	feeds, err := claimNextFeeds(ctx, s, concurrency)
 	if err != nil {
-		// Check if there are no feeds to fetch
-		if err == sql.ErrNoRows {
-			log.Printf("No feeds to fetch at the moment.")
-			return err
+		log.Printf("Error fetching next feeds: %v", err)
+		return stats, err
+	}
+
+	if len(feeds) == 0 {
+		log.Printf("No feeds to fetch at the moment.")
+		return stats, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]scrapeStats, len(feeds))
+	errs := make([]error, len(feeds))
+
+	for i, feed := range feeds {
+		wg.Add(1)
+		go func(i int, feed database.Feed) {
+			defer wg.Done()
+			results[i], errs[i] = scrapeFeed(ctx, s, feed)
+		}(i, feed)
+	}
+
+	wg.Wait()
+
+	for i := range feeds {
+		// Feeds abandoned because of shutdown count as neither
+		if errors.Is(errs[i], context.Canceled) {
+			continue
+		}
+		if errs[i] != nil {
+			stats.FeedsFailed++
+			continue
 		}
-		// Log other types of database errors
-		log.Printf("Error fetching next feed: %v", err)
-		return nil
+		stats.FeedsFetched++
+		stats.add(results[i])
 	}
 
+	return stats, errors.Join(errs...)
+}
+
+const (
+	baseFeedBackoff = time.Minute
+	maxFeedBackoff  = 24 * time.Hour
+)
+
+// feedBackoff returns how long to wait before retrying a feed that has failed
+// the given number of times in a row, doubling up to maxFeedBackoff.
+func feedBackoff(failures int32) time.Duration {
+	backoff := baseFeedBackoff
+	for i := int32(1); i < failures; i++ {
+		backoff *= 2
+		if backoff >= maxFeedBackoff {
+			return maxFeedBackoff
+		}
+	}
+	return backoff
+}
+
+// recordFeedFailure stores the error and pushes the feed's next attempt back
+// so a broken feed doesn't starve the healthy ones.
+func recordFeedFailure(ctx context.Context, s *state, feed database.Feed, fetchErr error) {
+	failures := feed.ConsecutiveFailures + 1
+	nextAttempt := time.Now().Add(feedBackoff(failures))
+
+	err := s.db.RecordFeedFailure(ctx, database.RecordFeedFailureParams{
+		LastError:     sql.NullString{String: fetchErr.Error(), Valid: true},
+		NextAttemptAt: sql.NullTime{Time: nextAttempt, Valid: true},
+		UpdatedAt:     time.Now(),
+		ID:            feed.ID,
+	})
+	if err != nil {
+		log.Printf("Error recording failure for feed %s: %v", feed.Url, err)
+		return
+	}
+
+	log.Printf("Feed %s has failed %d time(s) in a row, next attempt at %s", feed.Name, failures, nextAttempt.Format(time.RFC1123))
+}
+
+// postGUID returns the item's guid (or Atom id), falling back to its link so
+// an edited item keeps its identity. Only items with neither are identified
+// by a hash of their content.
+func postGUID(item RSSItem) string {
+	if guid := strings.TrimSpace(item.GUID); guid != "" {
+		return guid
+	}
+	if link := strings.TrimSpace(item.Link); link != "" {
+		return link
+	}
+	sum := sha256.Sum256([]byte(item.Link + "\n" + item.Title + "\n" + item.Description))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// feedFetchRetention is how long the fetch history behind feedstatus's
+// average is kept. The last successful fetch is stored on the feed itself.
+const feedFetchRetention = 30 * 24 * time.Hour
+
+// pruneFeedFetches deletes fetch history older than feedFetchRetention, so
+// feed_fetches doesn't grow without bound.
+func pruneFeedFetches(ctx context.Context, s *state) {
+	deleted, err := s.db.DeleteFeedFetchesBefore(ctx, time.Now().Add(-feedFetchRetention))
+	if err != nil {
+		log.Printf("Error pruning fetch history: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("Pruned %d old fetch record(s)", deleted)
+	}
+}
+
+// recordFeedFetch logs the outcome of a single fetch so feedstatus can report
+// on it later.
+func recordFeedFetch(ctx context.Context, s *state, feed database.Feed, result *fetchResult, fetchErr error) {
+	params := database.CreateFeedFetchParams{
+		FeedID:    feed.ID,
+		FetchedAt: time.Now(),
+		StatusCode: sql.NullInt32{
+			Int32: int32(result.StatusCode),
+			Valid: result.StatusCode != 0,
+		},
+		ItemCount: int32(len(result.Feed.Channel.Item)),
+	}
+	if fetchErr != nil {
+		params.Error = sql.NullString{String: fetchErr.Error(), Valid: true}
+	}
+
+	if err := s.db.CreateFeedFetch(ctx, params); err != nil {
+		log.Printf("Error recording fetch for feed %s: %v", feed.Url, err)
+	}
+}
+
+// scrapeFeed fetches a single feed and stores its new and edited posts,
+// returning how many of each there were. Cancelling ctx aborts the HTTP
+// request; once the body has been fetched the feed is always processed to
+// completion.
+func scrapeFeed(ctx context.Context, s *state, nextFeed database.Feed) (scrapeStats, error) {
 	log.Printf("Fetching feed: %s (%s)", nextFeed.Name, nextFeed.Url)
 
-	// Call `fetchFeed` to fetch and parse the feed
-	feed, err := fetchFeed(ctx, nextFeed.Url)
+	// Call `fetchFeed` to fetch and parse the feed, reusing the cached validators
+	result, err := fetchFeed(ctx, nextFeed.Url, feedCache{
+		ETag:         nextFeed.Etag.String,
+		LastModified: nextFeed.LastModified.String,
+	})
 	if err != nil {
+		// Shutting down is not the feed's fault, so leave it as it was
+		if ctx.Err() != nil {
+			log.Printf("Cancelled fetching feed %s", nextFeed.Url)
+			releaseFeedClaim(context.WithoutCancel(ctx), s, nextFeed)
+			return scrapeStats{}, ctx.Err()
+		}
 		log.Printf("Error fetching feed %s: %v", nextFeed.Url, err)
-		return err
+		recordFeedFetch(ctx, s, nextFeed, result, err)
+		recordFeedFailure(ctx, s, nextFeed, err)
+		return scrapeStats{}, err
+	}
+
+	// Don't let a shutdown leave the feed half processed
+	ctx = context.WithoutCancel(ctx)
+	var stats scrapeStats
+
+	recordFeedFetch(ctx, s, nextFeed, result, nil)
+
+	if result.NotModified {
+		log.Printf("Feed %s not modified since last fetch", nextFeed.Name)
+	}
+
+	// Remember the publisher's homepage so exports can include it
+	if link := result.Feed.Channel.Link; link != "" && link != nextFeed.SiteUrl.String {
+		err = s.db.SetFeedSiteURL(ctx, database.SetFeedSiteURLParams{
+			SiteUrl: sql.NullString{String: link, Valid: true},
+			ID:      nextFeed.ID,
+		})
+		if err != nil {
+			log.Printf("Error updating site URL for feed %s: %v", nextFeed.Url, err)
+		}
 	}
 
 	// Log or process the feed items
-	for _, item := range feed.Channel.Item {
+	for _, item := range result.Feed.Channel.Item {
 		postID := uuid.New()
 		now := time.Now()
 
-		// Parse the pub date (you'll need to handle different date formats)
-		pubDate, err := time.Parse(time.RFC1123Z, item.PubDate)
+		// Parse the pub date, falling back to the fetch time rather than
+		// dropping the post
+		pubDate, err := parsePubDate(item.PubDate)
 		if err != nil {
-			// Try alternative format if the first one fails
-			pubDate, err = time.Parse(time.RFC1123, item.PubDate)
-			if err != nil {
-				log.Printf("Error parsing date %s: %v", item.PubDate, err)
-				continue
-			}
+			log.Printf("Error parsing date for %s: %v", item.Link, err)
+			pubDate = now
+		}
+
+		guid := postGUID(item)
+
+		// Remember what the post looked like before so edits can be recorded
+		existing, err := s.db.GetPostByFeedAndGUID(ctx, database.GetPostByFeedAndGUIDParams{
+			FeedID: nextFeed.ID,
+			Guid:   guid,
+		})
+		if errors.Is(err, sql.ErrNoRows) && item.Link != "" {
+			// Posts stored before guids existed were given their url as guid,
+			// and guid-less posts were once keyed by a content hash; switch
+			// such a post to the current guid rather than duplicating it
+			var adopted database.AdoptLegacyPostGUIDRow
+			adopted, err = s.db.AdoptLegacyPostGUID(ctx, database.AdoptLegacyPostGUIDParams{
+				Guid:   guid,
+				FeedID: nextFeed.ID,
+				Url:    item.Link,
+			})
+			existing = database.GetPostByFeedAndGUIDRow(adopted)
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to look up post %s: %v", guid, err)
+			continue
 		}
+		isNew := errors.Is(err, sql.ErrNoRows)
 
-		// Try to create the post
-		_, err = s.db.CreatePost(ctx, database.CreatePostParams{
+		// Insert the post, or update it if the publisher has edited it
+		rows, err := s.db.UpsertPost(ctx, database.UpsertPostParams{
 			ID:        postID,
 			CreatedAt: now,
 			UpdatedAt: now,
@@ -120,29 +439,57 @@ func scrapeFeeds(ctx context.Context, s *state) error {
 			},
 			PublishedAt: pubDate,
 			FeedID:      nextFeed.ID,
+			Guid:        guid,
+			Content: sql.NullString{
+				String: item.Content,
+				Valid:  item.Content != "",
+			},
 		})
-
 		if err != nil {
-			// Check if it's a duplicate URL error
-			if strings.Contains(err.Error(), "duplicate key") {
-				continue // skip this post and move on
-			}
-			log.Printf("Failed to create post: %v", err)
+			log.Printf("Failed to save post: %v", err)
+			continue
+		}
+		if rows == 0 {
+			continue // unchanged
+		}
+
+		if isNew {
+			stats.PostsCreated++
 			continue
 		}
+
+		err = s.db.CreatePostRevision(ctx, database.CreatePostRevisionParams{
+			PostID:      existing.ID,
+			RevisedAt:   now,
+			Title:       existing.Title,
+			Description: existing.Description,
+			Content:     existing.Content,
+		})
+		if err != nil {
+			log.Printf("Failed to record revision of post %s: %v", existing.ID, err)
+		}
+		stats.PostsUpdated++
 	}
 
 	// Mark the feed as fetched in the database
 	err = s.db.MarkFeedFetched(ctx, database.MarkFeedFetchedParams{
 		LastFetchedAt: sql.NullTime{Time: time.Now(), Valid: true},
 		UpdatedAt:     time.Now(),
-		ID:            nextFeed.ID,
+		Etag: sql.NullString{
+			String: result.Cache.ETag,
+			Valid:  result.Cache.ETag != "",
+		},
+		LastModified: sql.NullString{
+			String: result.Cache.LastModified,
+			Valid:  result.Cache.LastModified != "",
+		},
+		ID: nextFeed.ID,
 	})
 	if err != nil {
 		log.Printf("Error updating feed %s: %v", nextFeed.Url, err)
-		return err
+		return stats, err
 	}
 
 	log.Printf("Successfully fetched and processed feed: %s", nextFeed.Name)
-	return nil
+	return stats, nil
 }
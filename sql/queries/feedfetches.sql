@@ -0,0 +1,43 @@
+-- name: CreateFeedFetch :exec
+INSERT INTO feed_fetches (feed_id, fetched_at, status_code, item_count, error)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: DeleteFeedFetchesBefore :execrows
+DELETE FROM feed_fetches
+WHERE fetched_at < $1;
+
+-- name: GetFeedStatuses :many
+SELECT
+    feeds.name,
+    feeds.url,
+    feeds.consecutive_failures,
+    feeds.last_error,
+    feeds.last_success_at,
+    fetch_stats.avg_items_per_fetch,
+    last_fetch.status_code AS last_status_code,
+    post_stats.newest_post_at
+FROM feeds
+LEFT JOIN (
+    SELECT
+        feed_id,
+        AVG(item_count) FILTER (WHERE error IS NULL AND status_code <> 304)::float8 AS avg_items_per_fetch
+    FROM feed_fetches
+    GROUP BY feed_id
+) AS fetch_stats ON fetch_stats.feed_id = feeds.id
+LEFT JOIN (
+    SELECT DISTINCT ON (feed_id) feed_id, status_code
+    FROM feed_fetches
+    ORDER BY feed_id, fetched_at DESC
+) AS last_fetch ON last_fetch.feed_id = feeds.id
+LEFT JOIN (
+    SELECT feed_id, MAX(published_at)::timestamp AS newest_post_at
+    FROM posts
+    GROUP BY feed_id
+) AS post_stats ON post_stats.feed_id = feeds.id
+ORDER BY feeds.name;
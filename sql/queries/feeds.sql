@@ -16,4 +16,9 @@ FROM feeds
 INNER JOIN users ON feeds.user_id = users.id;
 
 -- name: GetFeedByURL :one
-SELECT * FROM feeds WHERE url = $1;
\ No newline at end of file
+SELECT * FROM feeds WHERE url = $1;
+
+-- name: SetFeedSiteURL :exec
+UPDATE feeds
+SET site_url = $1
+WHERE id = $2;
\ No newline at end of file
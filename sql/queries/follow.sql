@@ -25,7 +25,15 @@ INNER JOIN users ON users.id = inserted_feed_follow.user_id;
 SELECT 
     feed_follows.*,
     feeds.name AS feed_name,
-    users.name AS user_name
+    feeds.url AS feed_url,
+    feeds.site_url AS feed_site_url,
+    users.name AS user_name,
+    (
+        SELECT COUNT(*)
+        FROM posts
+        LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+        WHERE posts.feed_id = feed_follows.feed_id AND post_reads.post_id IS NULL
+    ) AS unread_count
 FROM feed_follows
 INNER JOIN feeds ON feeds.id = feed_follows.feed_id
 INNER JOIN users ON users.id = feed_follows.user_id
@@ -33,4 +41,9 @@ WHERE feed_follows.user_id = $1;
 
 -- name: UnfollowFeedForUser :exec
 DELETE FROM feed_follows
-WHERE feed_id = $1 AND user_id = $2;
\ No newline at end of file
+WHERE feed_id = $1 AND user_id = $2;
+
+-- name: SetFeedFollowFolder :exec
+UPDATE feed_follows
+SET folder = $1, updated_at = NOW()
+WHERE user_id = $2 AND feed_id = $3;
\ No newline at end of file
@@ -1,5 +1,18 @@
--- name: GetNextFeedToFetch :one
-SELECT * 
-FROM feeds
-ORDER BY last_fetched_at NULLS FIRST
-LIMIT 1;
\ No newline at end of file
+-- name: ClaimNextFeedsToFetch :many
+UPDATE feeds
+SET claimed_until = sqlc.arg('claimed_until')
+WHERE id IN (
+    SELECT id
+    FROM feeds
+    WHERE (next_attempt_at IS NULL OR next_attempt_at <= sqlc.arg('now'))
+      AND (claimed_until IS NULL OR claimed_until < sqlc.arg('now'))
+    ORDER BY last_fetched_at NULLS FIRST
+    LIMIT sqlc.arg('max_results')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: ReleaseFeedClaim :exec
+UPDATE feeds
+SET claimed_until = NULL
+WHERE id = $1;
@@ -1,4 +1,5 @@
 -- name: MarkFeedFetched :exec
 UPDATE feeds
-SET last_fetched_at = $1, updated_at = $2
-WHERE id = $3;
+SET last_fetched_at = $1, last_success_at = $1, updated_at = $2, etag = $3, last_modified = $4,
+    consecutive_failures = 0, last_error = NULL, next_attempt_at = NULL, claimed_until = NULL
+WHERE id = $5;
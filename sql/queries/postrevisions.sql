@@ -0,0 +1,14 @@
+-- name: CreatePostRevision :exec
+INSERT INTO post_revisions (post_id, revised_at, title, description, content)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5
+);
+
+-- name: GetPostRevisions :many
+SELECT * FROM post_revisions
+WHERE post_id = $1
+ORDER BY revised_at DESC;
@@ -1,5 +1,5 @@
--- name: CreatePost :one
-INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id)
+-- name: UpsertPost :execrows
+INSERT INTO posts (id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content)
 VALUES (
     $1,
     $2,
@@ -8,15 +8,68 @@ VALUES (
     $5,
     $6,
     $7,
-    $8
+    $8,
+    $9,
+    $10
 )
-RETURNING *;
+ON CONFLICT (feed_id, guid) DO UPDATE
+SET title = EXCLUDED.title,
+    url = EXCLUDED.url,
+    description = EXCLUDED.description,
+    content = EXCLUDED.content,
+    updated_at = EXCLUDED.updated_at
+WHERE posts.title IS DISTINCT FROM EXCLUDED.title
+   OR posts.description IS DISTINCT FROM EXCLUDED.description
+   OR posts.content IS DISTINCT FROM EXCLUDED.content
+   OR posts.url IS DISTINCT FROM EXCLUDED.url;
 
--- name: GetPostsForUser :many
-SELECT posts.*
+-- name: GetPostByFeedAndGUID :one
+SELECT id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content
+FROM posts
+WHERE feed_id = $1 AND guid = $2;
+
+-- Posts stored before guids existed had their guid backfilled from the url,
+-- and guid-less posts were once keyed by a hash of their content.
+-- name: AdoptLegacyPostGUID :one
+UPDATE posts
+SET guid = sqlc.arg('guid')
+WHERE feed_id = sqlc.arg('feed_id')
+  AND url = sqlc.arg('url')
+  AND (guid = sqlc.arg('url') OR guid LIKE 'sha256:%')
+RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id, guid, content;
+
+-- name: BrowsePostsNewest :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+  AND (sqlc.arg('include_read')::boolean OR post_reads.post_id IS NULL)
+  AND (sqlc.narg('feed')::text IS NULL OR feeds.url = sqlc.narg('feed')::text OR feeds.name = sqlc.narg('feed')::text)
+  AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since')::timestamp)
+  AND (sqlc.narg('until')::timestamp IS NULL OR posts.published_at < sqlc.narg('until')::timestamp)
+  AND (
+      sqlc.narg('cursor_published_at')::timestamp IS NULL
+      OR (posts.published_at, posts.id) < (sqlc.narg('cursor_published_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY posts.published_at DESC, posts.id DESC
+LIMIT sqlc.arg('max_results');
+
+-- name: BrowsePostsOldest :many
+SELECT posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id, posts.guid, posts.content
 FROM posts
 INNER JOIN feeds ON posts.feed_id = feeds.id
 INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
-WHERE feed_follows.user_id = $1
-ORDER BY posts.published_at DESC
-LIMIT $2;
+LEFT JOIN post_reads ON post_reads.post_id = posts.id AND post_reads.user_id = feed_follows.user_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+  AND (sqlc.arg('include_read')::boolean OR post_reads.post_id IS NULL)
+  AND (sqlc.narg('feed')::text IS NULL OR feeds.url = sqlc.narg('feed')::text OR feeds.name = sqlc.narg('feed')::text)
+  AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since')::timestamp)
+  AND (sqlc.narg('until')::timestamp IS NULL OR posts.published_at < sqlc.narg('until')::timestamp)
+  AND (
+      sqlc.narg('cursor_published_at')::timestamp IS NULL
+      OR (posts.published_at, posts.id) > (sqlc.narg('cursor_published_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+  )
+ORDER BY posts.published_at ASC, posts.id ASC
+LIMIT sqlc.arg('max_results');
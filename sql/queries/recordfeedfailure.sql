@@ -0,0 +1,5 @@
+-- name: RecordFeedFailure :exec
+UPDATE feeds
+SET consecutive_failures = consecutive_failures + 1, last_error = $1, next_attempt_at = $2, updated_at = $3,
+    claimed_until = NULL
+WHERE id = $4;
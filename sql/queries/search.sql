@@ -0,0 +1,23 @@
+-- name: SearchPostsForUser :many
+SELECT
+    posts.id,
+    posts.title,
+    posts.url,
+    posts.published_at,
+    feeds.name AS feed_name,
+    ts_rank(posts.search_vector, websearch_to_tsquery('english', sqlc.arg('query')::text)) AS rank,
+    ts_headline(
+        'english',
+        COALESCE(posts.description, posts.content, posts.title),
+        websearch_to_tsquery('english', sqlc.arg('query')::text),
+        'StartSel=**, StopSel=**, MaxWords=35, MinWords=15'
+    )::text AS snippet
+FROM posts
+INNER JOIN feeds ON posts.feed_id = feeds.id
+INNER JOIN feed_follows ON feeds.id = feed_follows.feed_id
+WHERE feed_follows.user_id = sqlc.arg('user_id')
+  AND posts.search_vector @@ websearch_to_tsquery('english', sqlc.arg('query')::text)
+  AND (sqlc.narg('feed')::text IS NULL OR feeds.url = sqlc.narg('feed')::text OR feeds.name = sqlc.narg('feed')::text)
+  AND (sqlc.narg('since')::timestamp IS NULL OR posts.published_at >= sqlc.narg('since')::timestamp)
+ORDER BY rank DESC, posts.published_at DESC
+LIMIT sqlc.arg('max_results');
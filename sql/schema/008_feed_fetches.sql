@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE feed_fetches (
+    id SERIAL PRIMARY KEY,
+    feed_id uuid NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    fetched_at TIMESTAMP NOT NULL,
+    status_code INTEGER,
+    item_count INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX feed_fetches_feed_id_fetched_at_idx ON feed_fetches (feed_id, fetched_at);
+
+-- +goose Down
+DROP TABLE feed_fetches;
@@ -0,0 +1,15 @@
+-- +goose Up
+ALTER TABLE posts ADD COLUMN content TEXT;
+
+CREATE TABLE post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id uuid NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    revised_at TIMESTAMP NOT NULL,
+    title TEXT NOT NULL,
+    description TEXT,
+    content TEXT
+);
+
+-- +goose Down
+DROP TABLE post_revisions;
+ALTER TABLE posts DROP COLUMN content;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE feeds ADD COLUMN last_success_at TIMESTAMP;
+
+UPDATE feeds
+SET last_success_at = (
+    SELECT MAX(fetched_at)
+    FROM feed_fetches
+    WHERE feed_fetches.feed_id = feeds.id AND feed_fetches.error IS NULL
+);
+
+-- +goose Down
+ALTER TABLE feeds DROP COLUMN last_success_at;